@@ -0,0 +1,269 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ASCIIClient implements Modbus ASCII client
+type ASCIIClient struct {
+	*GenericClient
+
+	config    *ASCIIConfig
+	port      serial.Port
+	writeOnly map[byte]bool
+	capture   *FrameCapture
+}
+
+// ASCIIConfig holds ASCII-specific configuration. ASCII devices commonly
+// use 7 data bits with even parity.
+type ASCIIConfig struct {
+	Device       string
+	Baud         int
+	DataBits     int
+	Parity       serial.Parity
+	StopBits     serial.StopBits
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// maxASCIIFrame is the length of the largest ASCII frame: ':', the slave
+// ID, the PDU and the LRC in hex, and CRLF
+const maxASCIIFrame = 1 + 2*(1+253+1) + 2
+
+// NewASCIIClient creates a new Modbus ASCII client
+func NewASCIIClient(config *ASCIIConfig) *ASCIIClient {
+	c := &ASCIIClient{
+		config: config,
+	}
+	c.GenericClient = NewGenericClient(c)
+	return c
+}
+
+// Connect opens the serial port
+func (c *ASCIIClient) Connect() error {
+	mode := &serial.Mode{
+		BaudRate: c.config.Baud,
+		DataBits: c.config.DataBits,
+		Parity:   c.config.Parity,
+		StopBits: c.config.StopBits,
+	}
+
+	port, err := serial.Open(c.config.Device, mode)
+	if err != nil {
+		return fmt.Errorf("failed to open serial port: %w", err)
+	}
+
+	// Set read timeout if specified
+	if c.config.ReadTimeout > 0 {
+		err = port.SetReadTimeout(c.config.ReadTimeout)
+		if err != nil {
+			port.Close()
+			return fmt.Errorf("failed to set read timeout: %w", err)
+		}
+	}
+
+	c.port = port
+	return nil
+}
+
+// Close closes the serial port
+func (c *ASCIIClient) Close() error {
+	if c.port != nil {
+		return c.port.Close()
+	}
+	return nil
+}
+
+// SetTimeout sets the communication timeout
+func (c *ASCIIClient) SetTimeout(timeout time.Duration) {
+	c.config.ReadTimeout = timeout
+	if c.port != nil {
+		c.port.SetReadTimeout(timeout)
+	}
+}
+
+// SetWriteOnly marks a device as reachable over a link that never returns
+// responses. Writes to it return as soon as they are sent and reads fail
+// with ErrWriteOnly.
+func (c *ASCIIClient) SetWriteOnly(slaveID byte, writeOnly bool) {
+	if c.writeOnly == nil {
+		c.writeOnly = make(map[byte]bool)
+	}
+	c.writeOnly[slaveID] = writeOnly
+}
+
+// SetCapture sends a copy of every frame sent and received to capture,
+// nil stops capturing
+func (c *ASCIIClient) SetCapture(capture *FrameCapture) {
+	c.capture = capture
+}
+
+// LRC computes the longitudinal redundancy check of data: the two's
+// complement of the sum of its bytes
+func LRC(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return -sum
+}
+
+// WriteFrame sends pdu to slaveID in an ASCII frame with its LRC.
+// Together with ReadFrame it allows custom request interleaving.
+func (c *ASCIIClient) WriteFrame(slaveID byte, pdu *PDU) error {
+	if c.port == nil {
+		return fmt.Errorf("port not open")
+	}
+
+	// Build ADU
+	adu := []byte{slaveID, pdu.FunctionCode}
+	adu = append(adu, pdu.Data...)
+	adu = append(adu, LRC(adu))
+
+	frame := make([]byte, 0, 1+2*len(adu)+2)
+	frame = append(frame, ':')
+	frame = append(frame, bytes.ToUpper([]byte(hex.EncodeToString(adu)))...)
+	frame = append(frame, '\r', '\n')
+
+	_, err := c.port.Write(frame)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	c.capture.capture(FrameSent, frame)
+	return nil
+}
+
+// ReadFrame reads the next ASCII frame, verifies its LRC and returns its
+// decoded content without the LRC. Characters before the ':' starting the
+// frame are discarded.
+func (c *ASCIIClient) ReadFrame() (*ADU, error) {
+	if c.port == nil {
+		return nil, fmt.Errorf("port not open")
+	}
+
+	// Read until CRLF - timeout handled by port
+	frame := make([]byte, 0, maxASCIIFrame)
+	buf := make([]byte, maxASCIIFrame)
+	for !bytes.HasSuffix(frame, []byte("\r\n")) {
+		n, err := c.port.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("read failed: %w", err)
+		}
+		if n == 0 {
+			return nil, ErrTimeout
+		}
+
+		frame = append(frame, buf[:n]...)
+		if start := bytes.LastIndexByte(frame, ':'); start > 0 {
+			frame = frame[start:]
+		}
+		if len(frame) > maxASCIIFrame {
+			return nil, ErrInvalidResponse
+		}
+	}
+
+	c.capture.capture(FrameReceived, frame)
+
+	if frame[0] != ':' {
+		return nil, ErrInvalidResponse
+	}
+
+	adu, err := hex.DecodeString(string(frame[1 : len(frame)-2]))
+	if err != nil || len(adu) < 3 {
+		return nil, ErrInvalidResponse
+	}
+
+	// Validate LRC
+	if LRC(adu) != 0 {
+		return nil, ErrInvalidLRC
+	}
+
+	// Remove LRC
+	adu = adu[:len(adu)-1]
+	return &ADU{
+		SlaveID: adu[0],
+		PDU: &PDU{
+			FunctionCode: adu[1],
+			Data:         adu[2:],
+		},
+	}, nil
+}
+
+// Endpoint returns the serial device
+func (c *ASCIIClient) Endpoint() string {
+	return c.config.Device
+}
+
+// Send sends a Modbus ASCII request once, implementing Transporter. The
+// request is not sent if ctx is already done, and its deadline bounds the
+// wait for the response.
+func (c *ASCIIClient) Send(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	if c.port == nil {
+		return nil, fmt.Errorf("port not open")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	writeOnly := c.writeOnly[slaveID] || slaveID == BroadcastID
+	if writeOnly && !isWriteFunction(pdu.FunctionCode) {
+		return nil, ErrWriteOnly
+	}
+
+	meta := readMetaFrom(ctx)
+	meta.sent(0)
+	err := c.WriteFrame(slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	if writeOnly {
+		return nil, nil
+	}
+
+	// Serial reads cannot be interrupted, a deadline shortens the timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if c.config.ReadTimeout <= 0 || timeout < c.config.ReadTimeout {
+			c.port.SetReadTimeout(max(timeout, time.Millisecond))
+			defer restoreReadTimeout(c.port, c.config.ReadTimeout)
+		}
+	}
+
+	adu, err := c.ReadFrame()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	meta.received(time.Now())
+
+	// Validate slave ID and function code
+	if adu.SlaveID != slaveID {
+		return nil, ErrInvalidSlaveID
+	}
+	if adu.PDU.FunctionCode&0x7F != pdu.FunctionCode {
+		return nil, ErrInvalidResponse
+	}
+
+	// Check for exception
+	if adu.PDU.FunctionCode == (pdu.FunctionCode | 0x80) {
+		if len(adu.PDU.Data) >= 1 {
+			return nil, &ModbusError{
+				FunctionCode:  pdu.FunctionCode,
+				ExceptionCode: adu.PDU.Data[0],
+			}
+		}
+		return nil, ErrInvalidResponse
+	}
+
+	return adu.PDU.Data, nil // Return data without slave ID and function code
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package modbus
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// asciiFrame builds an ASCII frame of adu with its LRC
+func asciiFrame(adu ...byte) []byte {
+	return []byte(":" + strings.ToUpper(hex.EncodeToString(append(adu, LRC(adu)))) + "\r\n")
+}
+
+func TestASCIIClientChecksResponses(t *testing.T) {
+	master, device := openPTY(t)
+
+	c := NewASCIIClient(&ASCIIConfig{Device: device, Baud: 9600, DataBits: 8, ReadTimeout: 500 * time.Millisecond})
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Answered with the function code of another request
+	go func() {
+		buf := make([]byte, maxASCIIFrame)
+		master.Read(buf)
+		master.Write(asciiFrame(1, FuncCodeReadInputRegisters, 2, 0, 7))
+	}()
+	_, err := c.ReadHoldingRegisters(1, 0, 1)
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("ReadHoldingRegisters() = %v, want %v", err, ErrInvalidResponse)
+	}
+
+	c.SetWriteOnly(2, true)
+	if _, err := c.ReadHoldingRegisters(2, 0, 1); !errors.Is(err, ErrWriteOnly) {
+		t.Errorf("ReadHoldingRegisters() = %v, want %v", err, ErrWriteOnly)
+	}
+	// Nothing is answered, the write returns once sent
+	if err := c.WriteSingleRegister(2, 0, 1); err != nil {
+		t.Errorf("WriteSingleRegister() = %v", err)
+	}
+}
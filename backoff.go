@@ -0,0 +1,126 @@
+package modbus
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff computes the delay before retrying after failures
+type Backoff interface {
+	// Delay returns the delay before retry number attempt, starting at 1
+	Delay(attempt int) time.Duration
+	// String names the strategy
+	String() string
+}
+
+// maxBackoff caps delays growing without Max instead of overflowing
+const maxBackoff = time.Duration(math.MaxInt64)
+
+// ConstantBackoff waits the same interval before every retry
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+// Delay returns the interval, whatever the attempt
+func (b ConstantBackoff) Delay(attempt int) time.Duration {
+	return b.Interval
+}
+
+// String returns "constant"
+func (b ConstantBackoff) String() string {
+	return "constant"
+}
+
+// ExponentialBackoff multiplies the delay after each failure, up to Max.
+// Jitter randomizes each delay by up to that fraction of it, so clients
+// failing together do not retry in lockstep.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64 // 2 if zero
+	Jitter     float64 // between 0 and 1
+}
+
+// Delay returns Initial multiplied attempt-1 times, capped at Max, or at
+// the largest duration if Max is zero, then jittered
+func (b ExponentialBackoff) Delay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	limit := float64(maxBackoff)
+	if b.Max > 0 {
+		limit = float64(b.Max)
+	}
+
+	delay := float64(b.Initial)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if delay >= limit {
+			delay = limit
+			break
+		}
+	}
+
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*rand.Float64() - 1)
+	}
+	// Converting a float beyond the range of Duration is undefined
+	if delay >= float64(maxBackoff) {
+		return maxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// String returns "exponential"
+func (b ExponentialBackoff) String() string {
+	return "exponential"
+}
+
+// FibonacciBackoff grows the delay along the Fibonacci sequence in
+// multiples of Unit (1, 1, 2, 3, 5, ...), up to Max
+type FibonacciBackoff struct {
+	Unit time.Duration
+	Max  time.Duration
+}
+
+// Delay returns Unit times the Fibonacci number of attempt, capped at Max,
+// or at the largest duration if Max is zero
+func (b FibonacciBackoff) Delay(attempt int) time.Duration {
+	if b.Unit <= 0 {
+		return 0
+	}
+	limit := maxBackoff
+	if b.Max > 0 {
+		limit = b.Max
+	}
+
+	a, next := time.Duration(1), time.Duration(1)
+	for i := 1; i < attempt; i++ {
+		if next > limit/b.Unit {
+			return limit
+		}
+		// The sum saturates, next is only used once checked above
+		a, next = next, a+min(next, maxBackoff-a)
+	}
+	return a * b.Unit
+}
+
+// String returns "fibonacci"
+func (b FibonacciBackoff) String() string {
+	return "fibonacci"
+}
+
+// BackoffFunc adapts a function to the Backoff interface
+type BackoffFunc func(attempt int) time.Duration
+
+// Delay calls f
+func (f BackoffFunc) Delay(attempt int) time.Duration {
+	return f(attempt)
+}
+
+// String returns "custom"
+func (f BackoffFunc) String() string {
+	return "custom"
+}
@@ -0,0 +1,52 @@
+package modbus
+
+import (
+	"testing"
+	"time"
+)
+
+// Without Max the delays grow up to the largest duration, never wrapping
+// around to negative ones
+func TestBackoffLargeAttempts(t *testing.T) {
+	backoffs := []Backoff{
+		ExponentialBackoff{Initial: time.Second},
+		ExponentialBackoff{Initial: time.Second, Jitter: 0.5},
+		ExponentialBackoff{Initial: time.Second, Multiplier: 10},
+		FibonacciBackoff{Unit: time.Second},
+		FibonacciBackoff{Unit: 1},
+	}
+	for _, b := range backoffs {
+		previous := time.Duration(0)
+		for attempt := 1; attempt <= 1000; attempt++ {
+			delay := b.Delay(attempt)
+			if delay <= 0 {
+				t.Fatalf("%+v: attempt %d: delay %v", b, attempt, delay)
+			}
+			if _, jittered := b.(ExponentialBackoff); !jittered && delay < previous {
+				t.Fatalf("%+v: attempt %d: delay %v shorter than %v", b, attempt, delay, previous)
+			}
+			previous = delay
+		}
+		if previous < time.Duration(1<<62) {
+			t.Errorf("%+v: last delay %v", b, previous)
+		}
+	}
+}
+
+func TestBackoffMax(t *testing.T) {
+	tests := []struct {
+		backoff Backoff
+		attempt int
+		want    time.Duration
+	}{
+		{ExponentialBackoff{Initial: time.Second, Max: time.Minute}, 3, 4 * time.Second},
+		{ExponentialBackoff{Initial: time.Second, Max: time.Minute}, 100, time.Minute},
+		{FibonacciBackoff{Unit: time.Second, Max: time.Minute}, 6, 8 * time.Second},
+		{FibonacciBackoff{Unit: time.Second, Max: time.Minute}, 100, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := tt.backoff.Delay(tt.attempt); got != tt.want {
+			t.Errorf("%+v: Delay(%d) = %v, want %v", tt.backoff, tt.attempt, got, tt.want)
+		}
+	}
+}
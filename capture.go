@@ -0,0 +1,83 @@
+package modbus
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// FrameDirection tells whether a captured frame was sent or received
+type FrameDirection int
+
+const (
+	FrameSent FrameDirection = iota
+	FrameReceived
+)
+
+func (d FrameDirection) String() string {
+	if d == FrameReceived {
+		return "received"
+	}
+	return "sent"
+}
+
+// CapturedFrame is a raw frame as it went over the wire
+type CapturedFrame struct {
+	Time      time.Time
+	Direction FrameDirection
+	Data      []byte
+}
+
+// FrameCapture hands raw frames to an analyzer through a buffered channel.
+// When the analyzer falls behind the oldest frames are dropped, so
+// capturing never slows the client down.
+type FrameCapture struct {
+	frames  chan CapturedFrame
+	dropped atomic.Uint64
+}
+
+// NewFrameCapture creates a capture buffering up to size frames, at
+// least one
+func NewFrameCapture(size int) *FrameCapture {
+	return &FrameCapture{
+		frames: make(chan CapturedFrame, max(size, 1)),
+	}
+}
+
+// Frames returns the channel delivering captured frames
+func (fc *FrameCapture) Frames() <-chan CapturedFrame {
+	return fc.frames
+}
+
+// Dropped returns the number of frames dropped because the buffer was full
+func (fc *FrameCapture) Dropped() uint64 {
+	return fc.dropped.Load()
+}
+
+// capture queues a copy of data, dropping the oldest frame if needed
+func (fc *FrameCapture) capture(direction FrameDirection, data ...[]byte) {
+	if fc == nil {
+		return
+	}
+
+	frame := CapturedFrame{
+		Time:      time.Now(),
+		Direction: direction,
+	}
+	for _, d := range data {
+		frame.Data = append(frame.Data, d...)
+	}
+
+	for {
+		select {
+		case fc.frames <- frame:
+			return
+		default:
+		}
+
+		select {
+		case <-fc.frames:
+			fc.dropped.Add(1)
+		default:
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package modbus
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFrameCaptureDropsOldest(t *testing.T) {
+	// A zero size must not make capture block or spin
+	fc := NewFrameCapture(0)
+	fc.capture(FrameSent, []byte{1})
+	fc.capture(FrameReceived, []byte{2}, []byte{3})
+
+	if n := fc.Dropped(); n != 1 {
+		t.Errorf("Dropped() = %d, want 1", n)
+	}
+	frame := <-fc.Frames()
+	if frame.Direction != FrameReceived || !bytes.Equal(frame.Data, []byte{2, 3}) {
+		t.Errorf("frame = %v %v, want received [2 3]", frame.Direction, frame.Data)
+	}
+}
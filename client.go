@@ -1,6 +1,7 @@
 package modbus
 
 import (
+	"context"
 	"time"
 )
 
@@ -16,9 +17,68 @@ type Client interface {
 	WriteSingleRegister(slaveID byte, address uint16, value uint16) error
 	WriteMultipleCoils(slaveID byte, address uint16, values []bool) error
 	WriteMultipleRegisters(slaveID byte, address uint16, values []uint16) error
+	MaskWriteRegister(slaveID byte, address uint16, andMask uint16, orMask uint16) error
 	SetTimeout(timeout time.Duration)
 }
 
+// ContextClient is a Client whose requests can be canceled or bounded by a
+// deadline through a context. A request fails with ctx.Err() when ctx is
+// done before its response arrives.
+type ContextClient interface {
+	Client
+	ReadCoilsContext(ctx context.Context, slaveID byte, address uint16, quantity uint16) ([]bool, error)
+	ReadDiscreteInputsContext(ctx context.Context, slaveID byte, address uint16, quantity uint16) ([]bool, error)
+	ReadHoldingRegistersContext(ctx context.Context, slaveID byte, address uint16, quantity uint16) ([]uint16, error)
+	ReadInputRegistersContext(ctx context.Context, slaveID byte, address uint16, quantity uint16) ([]uint16, error)
+	WriteSingleCoilContext(ctx context.Context, slaveID byte, address uint16, value bool) error
+	WriteSingleRegisterContext(ctx context.Context, slaveID byte, address uint16, value uint16) error
+	WriteMultipleCoilsContext(ctx context.Context, slaveID byte, address uint16, values []bool) error
+	WriteMultipleRegistersContext(ctx context.Context, slaveID byte, address uint16, values []uint16) error
+	MaskWriteRegisterContext(ctx context.Context, slaveID byte, address uint16, andMask uint16, orMask uint16) error
+}
+
+// readHoldingRegisters reads holding registers with the context method
+// of client if it has one, so that ctx bounds the request in flight
+func readHoldingRegisters(ctx context.Context, client Client, slaveID byte, address uint16, quantity uint16) ([]uint16, error) {
+	if cc, ok := client.(ContextClient); ok {
+		return cc.ReadHoldingRegistersContext(ctx, slaveID, address, quantity)
+	}
+	return client.ReadHoldingRegisters(slaveID, address, quantity)
+}
+
+// writeSingleRegister writes a holding register with the context method
+// of client if it has one
+func writeSingleRegister(ctx context.Context, client Client, slaveID byte, address uint16, value uint16) error {
+	if cc, ok := client.(ContextClient); ok {
+		return cc.WriteSingleRegisterContext(ctx, slaveID, address, value)
+	}
+	return client.WriteSingleRegister(slaveID, address, value)
+}
+
+// writeMultipleRegisters writes holding registers with the context method
+// of client if it has one
+func writeMultipleRegisters(ctx context.Context, client Client, slaveID byte, address uint16, values []uint16) error {
+	if cc, ok := client.(ContextClient); ok {
+		return cc.WriteMultipleRegistersContext(ctx, slaveID, address, values)
+	}
+	return client.WriteMultipleRegisters(slaveID, address, values)
+}
+
+// Transporter frames requests for a kind of link, such as MBAP over TCP
+// or RTU with its CRC over a serial line. A GenericClient implements the
+// function codes on top of it.
+type Transporter interface {
+	Connect() error
+	Close() error
+	SetTimeout(timeout time.Duration)
+	// Send sends pdu to slaveID once and returns the data of the response
+	// PDU, or a *ModbusError for an exception response. It gives up when
+	// ctx is done.
+	Send(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error)
+	// Endpoint identifies the link in errors: an address or a serial device
+	Endpoint() string
+}
+
 // ClientConfig holds common configuration
 type ClientConfig struct {
 	Timeout time.Duration
@@ -0,0 +1,50 @@
+package modbus
+
+// CommEventCounterReader reads the communication event counter of a device
+type CommEventCounterReader interface {
+	GetCommEventCounter(slaveID byte) (status uint16, count uint16, err error)
+}
+
+// CommEventDetector skips block reads of mostly static serial devices
+// while their communication event counter shows no message besides the
+// detector's own reads
+type CommEventDetector struct {
+	Client  CommEventCounterReader
+	SlaveID byte
+	// RequestsPerRead is the number of requests made by the read function,
+	// each one increments the device counter
+	RequestsPerRead int
+
+	expected uint16
+	primed   bool
+}
+
+// Poll fetches the event counter and calls read only if another message
+// reached the device since the last read, or on the first call.
+// It reports whether read was called.
+func (d *CommEventDetector) Poll(read func() error) (bool, error) {
+	_, count, err := d.Client.GetCommEventCounter(d.SlaveID)
+	if err != nil {
+		return false, err
+	}
+
+	if d.primed && count == d.expected {
+		return false, nil
+	}
+
+	err = read()
+	if err != nil {
+		// The number of successful requests is unknown, read again next time
+		d.primed = false
+		return true, err
+	}
+
+	d.expected = count + uint16(d.RequestsPerRead)
+	d.primed = true
+	return true, nil
+}
+
+// Reset forces the next Poll to read
+func (d *CommEventDetector) Reset() {
+	d.primed = false
+}
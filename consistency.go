@@ -0,0 +1,93 @@
+package modbus
+
+import (
+	"slices"
+)
+
+// ReadConsistent reads quantity registers starting at address, in as many
+// requests as needed, and reads them again to check nothing changed in
+// between. Values changing mid-read would otherwise be torn, such as a 32
+// bit value whose halves come from different updates. The block is read
+// up to attempts times, 3 if zero, before failing with ErrTornRead along
+// with the values of the last read.
+func ReadConsistent(read RegisterReader, slaveID byte, address uint16, quantity int, attempts int) ([]uint16, error) {
+	if attempts <= 0 {
+		attempts = 3
+	}
+
+	previous, err := readBlock(read, slaveID, address, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	for range attempts - 1 {
+		values, err := readBlock(read, slaveID, address, quantity)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Equal(values, previous) {
+			return values, nil
+		}
+		previous = values
+	}
+	return previous, ErrTornRead
+}
+
+// ReadConsistentWithSequence reads quantity registers starting at address
+// between two reads of a register the device changes on every update, such
+// as a sequence number or freeze counter at sequence. The block is
+// consistent if the sequence register did not change. It is read up to
+// attempts times, 3 if zero, before failing with ErrTornRead along with
+// the values of the last read.
+func ReadConsistentWithSequence(read RegisterReader, slaveID byte, sequence uint16, address uint16, quantity int, attempts int) ([]uint16, error) {
+	if attempts <= 0 {
+		attempts = 3
+	}
+
+	before, err := readBlock(read, slaveID, sequence, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []uint16
+	for range attempts {
+		values, err = readBlock(read, slaveID, address, quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		after, err := readBlock(read, slaveID, sequence, 1)
+		if err != nil {
+			return nil, err
+		}
+		if after[0] == before[0] {
+			return values, nil
+		}
+		before = after
+	}
+	return values, ErrTornRead
+}
+
+// readBlock reads quantity registers starting at address in requests of
+// at most maxReadRegisters
+func readBlock(read RegisterReader, slaveID byte, address uint16, quantity int) ([]uint16, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if int(address)+quantity > 0x10000 {
+		return nil, ErrInvalidAddress
+	}
+
+	block := make([]uint16, 0, quantity)
+	for offset, chunk := range chunks(quantity, maxReadRegisters) {
+		values, err := read(slaveID, address+uint16(offset), uint16(chunk))
+		if err != nil {
+			return nil, err
+		}
+		if len(values) < chunk {
+			return nil, ErrInvalidResponse
+		}
+		block = append(block, values[:chunk]...)
+	}
+	return block, nil
+}
@@ -0,0 +1,88 @@
+package modbus
+
+import (
+	"time"
+)
+
+// WordOrder is the order of registers in a value spanning several registers
+type WordOrder int
+
+const (
+	// HighWordFirst stores the most significant register first
+	HighWordFirst WordOrder = iota
+	// LowWordFirst stores the least significant register first
+	LowWordFirst
+)
+
+func (o WordOrder) String() string {
+	switch o {
+	case HighWordFirst:
+		return "high word first"
+	case LowWordFirst:
+		return "low word first"
+	}
+	return "unknown"
+}
+
+// RegisterReader reads registers, such as Client.ReadHoldingRegisters
+type RegisterReader func(slaveID byte, address uint16, quantity uint16) ([]uint16, error)
+
+// RegistersToUint64 combines 4 registers into a 64-bit value
+func RegistersToUint64(registers []uint16, order WordOrder) uint64 {
+	var value uint64
+	for i := 0; i < 4; i++ {
+		reg := registers[i]
+		if order == LowWordFirst {
+			reg = registers[3-i]
+		}
+		value = value<<16 | uint64(reg)
+	}
+	return value
+}
+
+// DetectCounterWordOrder infers the word order of a 64-bit counter by
+// reading it twice, interval apart. The counter only moves forward by a
+// small amount, so the order yielding the smallest forward step wins.
+// The counter must change between both reads, otherwise
+// ErrWordOrderUndetermined is returned.
+func DetectCounterWordOrder(read RegisterReader, slaveID byte, address uint16, interval time.Duration) (WordOrder, error) {
+	first, err := read(slaveID, address, 4)
+	if err != nil {
+		return HighWordFirst, err
+	}
+	if len(first) < 4 {
+		return HighWordFirst, ErrInvalidResponse
+	}
+
+	time.Sleep(interval)
+
+	second, err := read(slaveID, address, 4)
+	if err != nil {
+		return HighWordFirst, err
+	}
+	if len(second) < 4 {
+		return HighWordFirst, ErrInvalidResponse
+	}
+
+	highStep, highOK := counterStep(first, second, HighWordFirst)
+	lowStep, lowOK := counterStep(first, second, LowWordFirst)
+
+	switch {
+	case highOK && (!lowOK || highStep < lowStep):
+		return HighWordFirst, nil
+	case lowOK && (!highOK || lowStep < highStep):
+		return LowWordFirst, nil
+	}
+	return HighWordFirst, ErrWordOrderUndetermined
+}
+
+// counterStep returns how far the counter moved forward between two reads
+// in the given order, and false if it did not move forward
+func counterStep(first, second []uint16, order WordOrder) (uint64, bool) {
+	a := RegistersToUint64(first, order)
+	b := RegistersToUint64(second, order)
+	if b <= a {
+		return 0, false
+	}
+	return b - a, true
+}
@@ -2,14 +2,49 @@ package modbus
 
 // CRC16 calculates CRC-16 for Modbus RTU
 func CRC16(data []byte) uint16 {
-	crc := uint16(0xFFFF)
+	return ModbusCRC16.sum(data)
+}
+
+// AppendCRC appends CRC to data
+func AppendCRC(data []byte) []byte {
+	return ModbusCRC16.Append(data)
+}
+
+// CheckCRC verifies CRC of received data
+func CheckCRC(data []byte) bool {
+	return ModbusCRC16.Check(data)
+}
+
+// Checksum computes and verifies the error check of RTU frames
+type Checksum interface {
+	// Append returns data followed by its checksum
+	Append(data []byte) []byte
+	// Check verifies the checksum at the end of frame
+	Check(frame []byte) bool
+	// Size returns the number of checksum bytes
+	Size() int
+}
+
+// CRC16Checksum is a reflected CRC-16 with configurable polynomial,
+// initial value and byte order
+type CRC16Checksum struct {
+	Polynomial    uint16 // reflected polynomial
+	Init          uint16
+	HighByteFirst bool
+}
+
+// ModbusCRC16 is the standard Modbus RTU checksum
+var ModbusCRC16 = CRC16Checksum{Polynomial: 0xA001, Init: 0xFFFF}
+
+func (c CRC16Checksum) sum(data []byte) uint16 {
+	crc := c.Init
 
 	for _, b := range data {
 		crc ^= uint16(b)
 		for i := 0; i < 8; i++ {
 			if crc&0x0001 != 0 {
 				crc >>= 1
-				crc ^= 0xA001
+				crc ^= c.Polynomial
 			} else {
 				crc >>= 1
 			}
@@ -19,25 +54,33 @@ func CRC16(data []byte) uint16 {
 	return crc
 }
 
-// AppendCRC appends CRC to data
-func AppendCRC(data []byte) []byte {
-	crc := CRC16(data)
+func (c CRC16Checksum) Append(data []byte) []byte {
+	crc := c.sum(data)
 	result := make([]byte, len(data)+2)
 	copy(result, data)
-	result[len(data)] = byte(crc & 0xFF)
-	result[len(data)+1] = byte((crc >> 8) & 0xFF)
+	if c.HighByteFirst {
+		result[len(data)] = byte(crc >> 8)
+		result[len(data)+1] = byte(crc & 0xFF)
+	} else {
+		result[len(data)] = byte(crc & 0xFF)
+		result[len(data)+1] = byte(crc >> 8)
+	}
 	return result
 }
 
-// CheckCRC verifies CRC of received data
-func CheckCRC(data []byte) bool {
-	if len(data) < 3 {
+func (c CRC16Checksum) Check(frame []byte) bool {
+	if len(frame) < 3 {
 		return false
 	}
 
-	payload := data[:len(data)-2]
-	receivedCRC := uint16(data[len(data)-2]) | (uint16(data[len(data)-1]) << 8)
-	calculatedCRC := CRC16(payload)
+	lo, hi := frame[len(frame)-2], frame[len(frame)-1]
+	if c.HighByteFirst {
+		lo, hi = hi, lo
+	}
+	received := uint16(lo) | uint16(hi)<<8
+	return received == c.sum(frame[:len(frame)-2])
+}
 
-	return receivedCRC == calculatedCRC
+func (c CRC16Checksum) Size() int {
+	return 2
 }
@@ -0,0 +1,203 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ByteOrder is the order of the bytes within a register
+type ByteOrder int
+
+const (
+	// HighByteFirst sends the most significant byte of a register first,
+	// as the specification requires
+	HighByteFirst ByteOrder = iota
+	// LowByteFirst sends the least significant byte of a register first
+	LowByteFirst
+)
+
+func (o ByteOrder) String() string {
+	switch o {
+	case HighByteFirst:
+		return "high byte first"
+	case LowByteFirst:
+		return "low byte first"
+	}
+	return "unknown"
+}
+
+// Decoder converts registers to typed values. The zero value decodes
+// big-endian values (ABCD); LowWordFirst decodes word-swapped values
+// (CDAB), LowByteFirst byte-swapped values (BADC) and both little-endian
+// values (DCBA).
+type Decoder struct {
+	WordOrder WordOrder
+	ByteOrder ByteOrder
+}
+
+// value returns the big-endian bytes of the value held by the first
+// words registers
+func (d Decoder) value(registers []uint16, words int) ([]byte, error) {
+	if len(registers) < words {
+		return nil, fmt.Errorf("%w: %d registers for a %d-register value", ErrInvalidQuantity, len(registers), words)
+	}
+
+	b := make([]byte, 2*words)
+	for i := 0; i < words; i++ {
+		reg := registers[i]
+		if d.WordOrder == LowWordFirst {
+			reg = registers[words-1-i]
+		}
+		if d.ByteOrder == LowByteFirst {
+			reg = reg<<8 | reg>>8
+		}
+		binary.BigEndian.PutUint16(b[2*i:], reg)
+	}
+	return b, nil
+}
+
+// Uint16 decodes an unsigned 16-bit value from 1 register
+func (d Decoder) Uint16(registers []uint16) (uint16, error) {
+	b, err := d.value(registers, 1)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// Int16 decodes a signed 16-bit value from 1 register
+func (d Decoder) Int16(registers []uint16) (int16, error) {
+	v, err := d.Uint16(registers)
+	return int16(v), err
+}
+
+// Uint32 decodes an unsigned 32-bit value from 2 registers
+func (d Decoder) Uint32(registers []uint16) (uint32, error) {
+	b, err := d.value(registers, 2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// Int32 decodes a signed 32-bit value from 2 registers
+func (d Decoder) Int32(registers []uint16) (int32, error) {
+	v, err := d.Uint32(registers)
+	return int32(v), err
+}
+
+// Float32 decodes an IEEE 754 single precision value from 2 registers
+func (d Decoder) Float32(registers []uint16) (float32, error) {
+	v, err := d.Uint32(registers)
+	return math.Float32frombits(v), err
+}
+
+// Uint64 decodes an unsigned 64-bit value from 4 registers
+func (d Decoder) Uint64(registers []uint16) (uint64, error) {
+	b, err := d.value(registers, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// Int64 decodes a signed 64-bit value from 4 registers
+func (d Decoder) Int64(registers []uint16) (int64, error) {
+	v, err := d.Uint64(registers)
+	return int64(v), err
+}
+
+// Float64 decodes an IEEE 754 double precision value from 4 registers
+func (d Decoder) Float64(registers []uint16) (float64, error) {
+	v, err := d.Uint64(registers)
+	return math.Float64frombits(v), err
+}
+
+// String decodes text stored 2 characters per register in the byte
+// order, the word order does not apply. Trailing NULs and spaces are
+// removed.
+func (d Decoder) String(registers []uint16) string {
+	b := make([]byte, 2*len(registers))
+	for i, reg := range registers {
+		if d.ByteOrder == LowByteFirst {
+			reg = reg<<8 | reg>>8
+		}
+		binary.BigEndian.PutUint16(b[2*i:], reg)
+	}
+	return strings.TrimRight(string(b), "\x00 ")
+}
+
+// Uint32s decodes consecutive unsigned 32-bit values, 2 registers each
+func (d Decoder) Uint32s(registers []uint16) ([]uint32, error) {
+	return decodeAll(registers, 2, d.Uint32)
+}
+
+// Int32s decodes consecutive signed 32-bit values, 2 registers each
+func (d Decoder) Int32s(registers []uint16) ([]int32, error) {
+	return decodeAll(registers, 2, d.Int32)
+}
+
+// Float32s decodes consecutive single precision values, 2 registers each
+func (d Decoder) Float32s(registers []uint16) ([]float32, error) {
+	return decodeAll(registers, 2, d.Float32)
+}
+
+// Float64s decodes consecutive double precision values, 4 registers each
+func (d Decoder) Float64s(registers []uint16) ([]float64, error) {
+	return decodeAll(registers, 4, d.Float64)
+}
+
+// ReadFloat32s reads count single precision values starting at address
+func (d Decoder) ReadFloat32s(read RegisterReader, slaveID byte, address uint16, count int) ([]float32, error) {
+	registers, err := readBlock(read, slaveID, address, 2*count)
+	if err != nil {
+		return nil, err
+	}
+	return d.Float32s(registers)
+}
+
+// ReadInt32s reads count signed 32-bit values starting at address
+func (d Decoder) ReadInt32s(read RegisterReader, slaveID byte, address uint16, count int) ([]int32, error) {
+	registers, err := readBlock(read, slaveID, address, 2*count)
+	if err != nil {
+		return nil, err
+	}
+	return d.Int32s(registers)
+}
+
+// ReadUint32s reads count unsigned 32-bit values starting at address
+func (d Decoder) ReadUint32s(read RegisterReader, slaveID byte, address uint16, count int) ([]uint32, error) {
+	registers, err := readBlock(read, slaveID, address, 2*count)
+	if err != nil {
+		return nil, err
+	}
+	return d.Uint32s(registers)
+}
+
+// ReadString reads text of up to length characters starting at address
+func (d Decoder) ReadString(read RegisterReader, slaveID byte, address uint16, length int) (string, error) {
+	registers, err := readBlock(read, slaveID, address, (length+1)/2)
+	if err != nil {
+		return "", err
+	}
+	return d.String(registers), nil
+}
+
+// decodeAll decodes every value of size registers with decode
+func decodeAll[T any](registers []uint16, size int, decode func([]uint16) (T, error)) ([]T, error) {
+	if len(registers)%size != 0 {
+		return nil, fmt.Errorf("%w: %d registers for %d-register values", ErrInvalidQuantity, len(registers), size)
+	}
+
+	values := make([]T, 0, len(registers)/size)
+	for i := 0; i < len(registers); i += size {
+		v, err := decode(registers[i : i+size])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,116 @@
+package modbus
+
+import (
+	"context"
+)
+
+// MEI type of the Read Device Identification request
+const MEIReadDeviceIdentification = 0x0E
+
+// DeviceIDCode selects the objects read by ReadDeviceIdentification
+type DeviceIDCode byte
+
+const (
+	// DeviceIDBasic reads the vendor name, product code and revision
+	DeviceIDBasic DeviceIDCode = 0x01
+	// DeviceIDRegular adds the vendor URL, product and model names and
+	// user application name
+	DeviceIDRegular DeviceIDCode = 0x02
+	// DeviceIDExtended adds the private objects from 0x80
+	DeviceIDExtended DeviceIDCode = 0x03
+)
+
+// Standard device identification objects
+const (
+	ObjectVendorName          = 0x00
+	ObjectProductCode         = 0x01
+	ObjectMajorMinorRevision  = 0x02
+	ObjectVendorURL           = 0x03
+	ObjectProductName         = 0x04
+	ObjectModelName           = 0x05
+	ObjectUserApplicationName = 0x06
+)
+
+// DeviceIdentification holds the identification objects of a device
+type DeviceIdentification struct {
+	VendorName          string
+	ProductCode         string
+	MajorMinorRevision  string
+	VendorURL           string
+	ProductName         string
+	ModelName           string
+	UserApplicationName string
+	// ConformityLevel is the identification level the device supports
+	ConformityLevel byte
+	// Objects holds every object received by ID, including the standard
+	// ones above and the extended objects
+	Objects map[byte][]byte
+}
+
+// requestFunc sends a request PDU and returns the response data
+type requestFunc func(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error)
+
+// readDeviceIdentification reads the objects of code, sending new
+// requests while the device reports more objects follow
+func readDeviceIdentification(ctx context.Context, send requestFunc, slaveID byte, code DeviceIDCode) (*DeviceIdentification, error) {
+	if code < DeviceIDBasic || code > DeviceIDExtended {
+		return nil, ErrInvalidQuantity
+	}
+
+	id := &DeviceIdentification{
+		Objects: make(map[byte][]byte),
+	}
+
+	// Object IDs must increase, so a device cannot make this loop forever
+	objectID := byte(0)
+	for frames := 1; ; frames++ {
+		response, err := send(ctx, slaveID, &PDU{
+			FunctionCode: FuncCodeEncapsulatedInterface,
+			Data:         []byte{MEIReadDeviceIdentification, byte(code), objectID},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// MEI type, code, conformity level, more follows, next object ID
+		// and number of objects, then the objects
+		if len(response) < 6 || response[0] != MEIReadDeviceIdentification {
+			return nil, ErrInvalidResponse
+		}
+		id.ConformityLevel = response[2]
+		moreFollows := response[3] == 0xFF
+		nextObjectID := response[4]
+		count := int(response[5])
+
+		objects := response[6:]
+		for range count {
+			if len(objects) < 2 || len(objects) < 2+int(objects[1]) {
+				return nil, ErrInvalidResponse
+			}
+			length := int(objects[1])
+			id.Objects[objects[0]] = append([]byte(nil), objects[2:2+length]...)
+			objects = objects[2+length:]
+		}
+		reportProgress(ctx, Progress{Name: "read device identification", Done: frames})
+
+		if !moreFollows {
+			id.decodeObjects()
+			return id, nil
+		}
+		if nextObjectID <= objectID {
+			return nil, ErrInvalidResponse
+		}
+		objectID = nextObjectID
+	}
+}
+
+// decodeObjects fills the named fields from the standard objects
+func (id *DeviceIdentification) decodeObjects() {
+	id.VendorName = string(id.Objects[ObjectVendorName])
+	id.ProductCode = string(id.Objects[ObjectProductCode])
+	id.MajorMinorRevision = string(id.Objects[ObjectMajorMinorRevision])
+	id.VendorURL = string(id.Objects[ObjectVendorURL])
+	id.ProductName = string(id.Objects[ObjectProductName])
+	id.ModelName = string(id.Objects[ObjectModelName])
+	id.UserApplicationName = string(id.Objects[ObjectUserApplicationName])
+}
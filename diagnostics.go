@@ -0,0 +1,114 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+)
+
+// Diagnostics sub-functions
+const (
+	DiagReturnQueryData            = 0x00
+	DiagRestartCommunications      = 0x01
+	DiagReturnDiagnosticRegister   = 0x02
+	DiagClearCountersAndRegister   = 0x0A
+	DiagReturnBusMessageCount      = 0x0B
+	DiagReturnBusCommErrorCount    = 0x0C
+	DiagReturnBusExceptionCount    = 0x0D
+	DiagReturnServerMessageCount   = 0x0E
+	DiagReturnServerNoRespCount    = 0x0F
+	DiagReturnServerNAKCount       = 0x10
+	DiagReturnServerBusyCount      = 0x11
+	DiagReturnBusCharOverrunCount  = 0x12
+	DiagClearOverrunCounterAndFlag = 0x14
+)
+
+// DiagnosticCounter selects a counter read by ReadDiagnosticCounter
+type DiagnosticCounter uint16
+
+const (
+	// CounterBusMessages counts the messages seen on the bus
+	CounterBusMessages DiagnosticCounter = DiagReturnBusMessageCount
+	// CounterBusCommErrors counts the CRC errors seen on the bus
+	CounterBusCommErrors DiagnosticCounter = DiagReturnBusCommErrorCount
+	// CounterBusExceptions counts the exception responses sent
+	CounterBusExceptions DiagnosticCounter = DiagReturnBusExceptionCount
+	// CounterServerMessages counts the messages addressed to the device
+	CounterServerMessages DiagnosticCounter = DiagReturnServerMessageCount
+	// CounterServerNoResponses counts the messages left unanswered
+	CounterServerNoResponses DiagnosticCounter = DiagReturnServerNoRespCount
+	// CounterServerNAKs counts the NAK exception responses sent
+	CounterServerNAKs DiagnosticCounter = DiagReturnServerNAKCount
+	// CounterServerBusy counts the busy exception responses sent
+	CounterServerBusy DiagnosticCounter = DiagReturnServerBusyCount
+	// CounterBusCharOverruns counts the messages lost to overruns
+	CounterBusCharOverruns DiagnosticCounter = DiagReturnBusCharOverrunCount
+)
+
+// diagnostic sends a diagnostics request and returns the data of the
+// response after checking the sub-function is echoed
+func diagnostic(ctx context.Context, send requestFunc, slaveID byte, subFunction uint16, data []byte) ([]byte, error) {
+	request := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(request[0:2], subFunction)
+	copy(request[2:], data)
+
+	response, err := send(ctx, slaveID, &PDU{
+		FunctionCode: FuncCodeDiagnostics,
+		Data:         request,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) < 2 || binary.BigEndian.Uint16(response[0:2]) != subFunction {
+		return nil, ErrInvalidResponse
+	}
+	return response[2:], nil
+}
+
+// returnQueryData sends data in a loopback request and checks it is echoed
+func returnQueryData(ctx context.Context, send requestFunc, slaveID byte, data []byte) error {
+	if len(data) > 250 {
+		return ErrInvalidQuantity
+	}
+
+	response, err := diagnostic(ctx, send, slaveID, DiagReturnQueryData, data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(response, data) {
+		return ErrInvalidResponse
+	}
+	return nil
+}
+
+// restartCommunications restarts the serial line of a device, optionally
+// clearing its communication event log
+func restartCommunications(ctx context.Context, send requestFunc, slaveID byte, clearLog bool) error {
+	data := []byte{0x00, 0x00}
+	if clearLog {
+		data[0] = 0xFF
+	}
+
+	response, err := diagnostic(ctx, send, slaveID, DiagRestartCommunications, data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(response, data) {
+		return ErrInvalidResponse
+	}
+	return nil
+}
+
+// diagnosticWord sends a diagnostics request with empty data and returns
+// the word answered
+func diagnosticWord(ctx context.Context, send requestFunc, slaveID byte, subFunction uint16) (uint16, error) {
+	response, err := diagnostic(ctx, send, slaveID, subFunction, []byte{0x00, 0x00})
+	if err != nil {
+		return 0, err
+	}
+	if len(response) < 2 {
+		return 0, ErrInvalidResponse
+	}
+	return binary.BigEndian.Uint16(response[0:2]), nil
+}
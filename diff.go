@@ -0,0 +1,50 @@
+package modbus
+
+// WriteRegistersDiff reads the holding registers starting at address and
+// writes only the contiguous ranges that differ from desired, leaving
+// registers that already hold the desired value untouched. It returns the
+// number of registers written.
+func WriteRegistersDiff(client Client, slaveID byte, address uint16, desired []uint16) (int, error) {
+	if len(desired) == 0 {
+		return 0, ErrInvalidQuantity
+	}
+
+	current := make([]uint16, 0, len(desired))
+	for offset, quantity := range chunks(len(desired), maxReadRegisters) {
+		values, err := client.ReadHoldingRegisters(slaveID, address+uint16(offset), uint16(quantity))
+		if err != nil {
+			return 0, err
+		}
+		if len(values) < quantity {
+			return 0, ErrInvalidResponse
+		}
+		current = append(current, values[:quantity]...)
+	}
+
+	written := 0
+	for start := 0; start < len(desired); {
+		if current[start] == desired[start] {
+			start++
+			continue
+		}
+
+		end := start + 1
+		for end < len(desired) && end-start < maxWriteRegisters && current[end] != desired[end] {
+			end++
+		}
+
+		var err error
+		if end-start == 1 {
+			err = client.WriteSingleRegister(slaveID, address+uint16(start), desired[start])
+		} else {
+			err = client.WriteMultipleRegisters(slaveID, address+uint16(start), desired[start:end])
+		}
+		if err != nil {
+			return written, err
+		}
+
+		written += end - start
+		start = end
+	}
+	return written, nil
+}
@@ -0,0 +1,135 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// RegisterWriter writes registers, such as Client.WriteMultipleRegisters
+type RegisterWriter func(slaveID byte, address uint16, values []uint16) error
+
+// Encoder converts typed values to registers, the reverse of a Decoder
+// with the same orders
+type Encoder struct {
+	WordOrder WordOrder
+	ByteOrder ByteOrder
+}
+
+// registers returns the registers holding the big-endian bytes b
+func (e Encoder) registers(b []byte) []uint16 {
+	words := len(b) / 2
+	registers := make([]uint16, words)
+	for i := 0; i < words; i++ {
+		reg := binary.BigEndian.Uint16(b[2*i:])
+		if e.ByteOrder == LowByteFirst {
+			reg = reg<<8 | reg>>8
+		}
+		if e.WordOrder == LowWordFirst {
+			registers[words-1-i] = reg
+		} else {
+			registers[i] = reg
+		}
+	}
+	return registers
+}
+
+// Uint16 encodes an unsigned 16-bit value in 1 register
+func (e Encoder) Uint16(v uint16) []uint16 {
+	return e.registers(binary.BigEndian.AppendUint16(nil, v))
+}
+
+// Int16 encodes a signed 16-bit value in 1 register
+func (e Encoder) Int16(v int16) []uint16 {
+	return e.Uint16(uint16(v))
+}
+
+// Uint32 encodes an unsigned 32-bit value in 2 registers
+func (e Encoder) Uint32(v uint32) []uint16 {
+	return e.registers(binary.BigEndian.AppendUint32(nil, v))
+}
+
+// Int32 encodes a signed 32-bit value in 2 registers
+func (e Encoder) Int32(v int32) []uint16 {
+	return e.Uint32(uint32(v))
+}
+
+// Float32 encodes an IEEE 754 single precision value in 2 registers
+func (e Encoder) Float32(v float32) []uint16 {
+	return e.Uint32(math.Float32bits(v))
+}
+
+// Uint64 encodes an unsigned 64-bit value in 4 registers
+func (e Encoder) Uint64(v uint64) []uint16 {
+	return e.registers(binary.BigEndian.AppendUint64(nil, v))
+}
+
+// Int64 encodes a signed 64-bit value in 4 registers
+func (e Encoder) Int64(v int64) []uint16 {
+	return e.Uint64(uint64(v))
+}
+
+// Float64 encodes an IEEE 754 double precision value in 4 registers
+func (e Encoder) Float64(v float64) []uint16 {
+	return e.Uint64(math.Float64bits(v))
+}
+
+// String encodes text in length characters, 2 per register in the byte
+// order, padded with NULs. Text longer than length is an error.
+func (e Encoder) String(s string, length int) ([]uint16, error) {
+	if len(s) > length {
+		return nil, fmt.Errorf("%w: %d characters for %d", ErrInvalidQuantity, len(s), length)
+	}
+
+	b := make([]byte, 2*((length+1)/2))
+	copy(b, s)
+	registers := make([]uint16, len(b)/2)
+	for i := range registers {
+		reg := binary.BigEndian.Uint16(b[2*i:])
+		if e.ByteOrder == LowByteFirst {
+			reg = reg<<8 | reg>>8
+		}
+		registers[i] = reg
+	}
+	return registers, nil
+}
+
+// WriteUint32 writes an unsigned 32-bit value at address
+func (e Encoder) WriteUint32(write RegisterWriter, slaveID byte, address uint16, v uint32) error {
+	return write(slaveID, address, e.Uint32(v))
+}
+
+// WriteInt32 writes a signed 32-bit value at address
+func (e Encoder) WriteInt32(write RegisterWriter, slaveID byte, address uint16, v int32) error {
+	return write(slaveID, address, e.Int32(v))
+}
+
+// WriteFloat32 writes a single precision value at address
+func (e Encoder) WriteFloat32(write RegisterWriter, slaveID byte, address uint16, v float32) error {
+	return write(slaveID, address, e.Float32(v))
+}
+
+// WriteUint64 writes an unsigned 64-bit value at address
+func (e Encoder) WriteUint64(write RegisterWriter, slaveID byte, address uint16, v uint64) error {
+	return write(slaveID, address, e.Uint64(v))
+}
+
+// WriteInt64 writes a signed 64-bit value at address
+func (e Encoder) WriteInt64(write RegisterWriter, slaveID byte, address uint16, v int64) error {
+	return write(slaveID, address, e.Int64(v))
+}
+
+// WriteFloat64 writes a double precision value at address
+func (e Encoder) WriteFloat64(write RegisterWriter, slaveID byte, address uint16, v float64) error {
+	return write(slaveID, address, e.Float64(v))
+}
+
+// WriteString writes text in length characters at address, padded with
+// NULs
+func (e Encoder) WriteString(write RegisterWriter, slaveID byte, address uint16, s string, length int) error {
+	registers, err := e.String(s, length)
+	if err != nil {
+		return err
+	}
+	return write(slaveID, address, registers)
+}
@@ -0,0 +1,151 @@
+// Package events defines the events published by bridges and sinks, with
+// stable JSON encodings so every consumer sees the same schema.
+//
+// Every event is encoded as a JSON object whose "type" field names its
+// kind, followed by the fields of the event.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Event types, written in the "type" field of every encoded event
+const (
+	TypeConnection  = "connection"
+	TypeValueChange = "value_change"
+	TypeAlarm       = "alarm"
+	TypeWriteAudit  = "write_audit"
+)
+
+// Tables written in the "table" field of value changes
+const (
+	TableCoils            = "coils"
+	TableDiscreteInputs   = "discrete_inputs"
+	TableHoldingRegisters = "holding_registers"
+	TableInputRegisters   = "input_registers"
+)
+
+// ErrUnknownType is returned by Decode for an unknown or missing type
+var ErrUnknownType = errors.New("unknown event type")
+
+// Event is implemented by every event of this package
+type Event interface {
+	EventType() string
+}
+
+// ConnectionEvent reports a connection going online or offline
+type ConnectionEvent struct {
+	Source string    `json:"source"`
+	Online bool      `json:"online"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Error  string    `json:"error,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// ValueChange reports a coil or register changing value. Coils and
+// discrete inputs are 0 or 1.
+type ValueChange struct {
+	Source   string    `json:"source"`
+	UnitID   byte      `json:"unit_id"`
+	Table    string    `json:"table"`
+	Address  uint16    `json:"address"`
+	Name     string    `json:"name,omitempty"`
+	Previous uint16    `json:"previous"`
+	Value    uint16    `json:"value"`
+	Time     time.Time `json:"time"`
+}
+
+// AlarmEvent reports an alarm being raised or cleared
+type AlarmEvent struct {
+	Source   string    `json:"source"`
+	Name     string    `json:"name"`
+	Severity string    `json:"severity,omitempty"`
+	Active   bool      `json:"active"`
+	Message  string    `json:"message,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// WriteAudit records a write sent to a device and its outcome
+type WriteAudit struct {
+	Source       string    `json:"source"`
+	UnitID       byte      `json:"unit_id"`
+	FunctionCode byte      `json:"function_code"`
+	Address      uint16    `json:"address"`
+	Values       []uint16  `json:"values"`
+	User         string    `json:"user,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+func (ConnectionEvent) EventType() string { return TypeConnection }
+func (ValueChange) EventType() string     { return TypeValueChange }
+func (AlarmEvent) EventType() string      { return TypeAlarm }
+func (WriteAudit) EventType() string      { return TypeWriteAudit }
+
+func (e ConnectionEvent) MarshalJSON() ([]byte, error) {
+	type event ConnectionEvent
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		event
+	}{TypeConnection, event(e)})
+}
+
+func (e ValueChange) MarshalJSON() ([]byte, error) {
+	type event ValueChange
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		event
+	}{TypeValueChange, event(e)})
+}
+
+func (e AlarmEvent) MarshalJSON() ([]byte, error) {
+	type event AlarmEvent
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		event
+	}{TypeAlarm, event(e)})
+}
+
+func (e WriteAudit) MarshalJSON() ([]byte, error) {
+	type event WriteAudit
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		event
+	}{TypeWriteAudit, event(e)})
+}
+
+// Decode decodes an event of any type of this package
+func Decode(data []byte) (Event, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	err := json.Unmarshal(data, &header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch header.Type {
+	case TypeConnection:
+		return decode[ConnectionEvent](data)
+	case TypeValueChange:
+		return decode[ValueChange](data)
+	case TypeAlarm:
+		return decode[AlarmEvent](data)
+	case TypeWriteAudit:
+		return decode[WriteAudit](data)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownType, header.Type)
+}
+
+func decode[T Event](data []byte) (Event, error) {
+	var event T
+	err := json.Unmarshal(data, &event)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SamyFrancelet/modbus"
+	"go.bug.st/serial"
+)
+
+func main() {
+	// Create ASCII client
+	config := &modbus.ASCIIConfig{
+		Device:      "/dev/ttyUSB0",
+		Baud:        9600,
+		DataBits:    7,
+		Parity:      serial.EvenParity,
+		StopBits:    serial.OneStopBit,
+		ReadTimeout: 1 * time.Second,
+	}
+
+	client := modbus.NewASCIIClient(config)
+
+	// Connect
+	err := client.Connect()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	slaveID := byte(1)
+
+	// Read holding registers at address 0
+	holdingRegs, err := client.ReadHoldingRegisters(slaveID, 0, 10)
+	if err != nil {
+		log.Printf("Error reading holding registers: %v", err)
+	} else {
+		fmt.Printf("Holding registers (0-9): %v\n", holdingRegs)
+	}
+
+	// Write to holding register 1
+	err = client.WriteSingleRegister(slaveID, 1, 1234)
+	if err != nil {
+		log.Printf("Error writing register: %v", err)
+	} else {
+		fmt.Println("Successfully wrote 1234 to holding register 1")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/SamyFrancelet/modbus"
+	"go.bug.st/serial"
+)
+
+func main() {
+	// Create the data served to the master
+	store := modbus.NewMemoryStore()
+
+	// Initialize holding registers 0-9
+	err := store.WriteHoldingRegisters(1, 0, []uint16{0, 10, 20, 30, 40, 50, 60, 70, 80, 90})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Create RTU slave with ID 1
+	config := &modbus.RTUConfig{
+		Device:   "/dev/ttyUSB0",
+		Baud:     9600,
+		DataBits: 8,
+		Parity:   serial.NoParity,
+		StopBits: serial.OneStopBit,
+	}
+
+	server := modbus.NewRTUServer(config, 1, store)
+
+	// Stop on Ctrl+C
+	go func() {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		<-interrupt
+		server.Close()
+	}()
+
+	log.Println("Serving Modbus RTU slave 1 on /dev/ttyUSB0")
+	err = server.ListenAndServe()
+	if err != nil && err != modbus.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
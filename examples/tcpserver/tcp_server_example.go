@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/SamyFrancelet/modbus"
+)
+
+func main() {
+	// Create the data served to clients
+	store := modbus.NewMemoryStore()
+
+	// Initialize holding registers 0-9 and input registers 0-9
+	err := store.WriteHoldingRegisters(0, 0, []uint16{0, 10, 20, 30, 40, 50, 60, 70, 80, 90})
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = store.WriteInputRegisters(0, []uint16{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Create TCP server
+	server := modbus.NewTCPServer("localhost:502", store)
+
+	// Stop on Ctrl+C
+	go func() {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		<-interrupt
+		server.Close()
+	}()
+
+	log.Println("Serving Modbus TCP on localhost:502")
+	err = server.ListenAndServe()
+	if err != nil && err != modbus.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
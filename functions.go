@@ -0,0 +1,152 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// FunctionCategory classifies function codes
+type FunctionCategory int
+
+const (
+	CategoryRead FunctionCategory = iota
+	CategoryWrite
+	// CategoryReadWrite writes and reads in one request
+	CategoryReadWrite
+	CategoryDiagnostic
+	CategoryOther
+)
+
+func (c FunctionCategory) String() string {
+	switch c {
+	case CategoryRead:
+		return "read"
+	case CategoryWrite:
+		return "write"
+	case CategoryReadWrite:
+		return "read/write"
+	case CategoryDiagnostic:
+		return "diagnostic"
+	case CategoryOther:
+		return "other"
+	}
+	return "unknown"
+}
+
+// PDULength returns the length of a PDU, function code included, from its
+// first bytes, false while they are too few or if the length depends on
+// something else than the PDU
+type PDULength func(pdu []byte) (int, bool)
+
+// FunctionInfo describes a function code
+type FunctionInfo struct {
+	Code     byte
+	Name     string
+	Category FunctionCategory
+	// RequestLength and ResponseLength are nil when the length cannot be
+	// determined from the PDU. Exception responses are always 2 bytes.
+	RequestLength  PDULength
+	ResponseLength PDULength
+}
+
+// fixedLength is the length of PDUs of n bytes
+func fixedLength(n int) PDULength {
+	return func([]byte) (int, bool) {
+		return n, true
+	}
+}
+
+// byteCountLength is the length of PDUs holding the count of the bytes
+// following it at offset, size bytes wide
+func byteCountLength(offset int, size int) PDULength {
+	return func(pdu []byte) (int, bool) {
+		if len(pdu) < offset+size {
+			return 0, false
+		}
+		if size == 2 {
+			return offset + size + int(binary.BigEndian.Uint16(pdu[offset:])), true
+		}
+		return offset + size + int(pdu[offset]), true
+	}
+}
+
+var (
+	functionsMu sync.RWMutex
+	functions   = map[byte]FunctionInfo{}
+)
+
+func init() {
+	for _, info := range []FunctionInfo{
+		{FuncCodeReadCoils, "read coils", CategoryRead, fixedLength(5), byteCountLength(1, 1)},
+		{FuncCodeReadDiscreteInputs, "read discrete inputs", CategoryRead, fixedLength(5), byteCountLength(1, 1)},
+		{FuncCodeReadHoldingRegisters, "read holding registers", CategoryRead, fixedLength(5), byteCountLength(1, 1)},
+		{FuncCodeReadInputRegisters, "read input registers", CategoryRead, fixedLength(5), byteCountLength(1, 1)},
+		{FuncCodeWriteSingleCoil, "write single coil", CategoryWrite, fixedLength(5), fixedLength(5)},
+		{FuncCodeWriteSingleRegister, "write single register", CategoryWrite, fixedLength(5), fixedLength(5)},
+		{FuncCodeDiagnostics, "diagnostics", CategoryDiagnostic, nil, nil},
+		{FuncCodeGetCommEventCounter, "get comm event counter", CategoryDiagnostic, fixedLength(1), fixedLength(5)},
+		{FuncCodeWriteMultipleCoils, "write multiple coils", CategoryWrite, byteCountLength(5, 1), fixedLength(5)},
+		{FuncCodeWriteMultipleRegisters, "write multiple registers", CategoryWrite, byteCountLength(5, 1), fixedLength(5)},
+		{FuncCodeMaskWriteRegister, "mask write register", CategoryWrite, fixedLength(7), fixedLength(7)},
+		{FuncCodeReadWriteMultipleRegisters, "read/write multiple registers", CategoryReadWrite, byteCountLength(9, 1), byteCountLength(1, 1)},
+		{FuncCodeReadFIFOQueue, "read FIFO queue", CategoryRead, fixedLength(3), byteCountLength(1, 2)},
+		{FuncCodeEncapsulatedInterface, "encapsulated interface transport", CategoryOther, nil, nil},
+	} {
+		functions[info.Code] = info
+	}
+}
+
+// RegisterFunction adds a vendor function code to the registry, so that
+// it is named and framed like the standard ones. Codes already registered
+// and exception codes, with the high bit set, are rejected.
+func RegisterFunction(info FunctionInfo) error {
+	if info.Code == 0 || info.Code&0x80 != 0 {
+		return fmt.Errorf("%w: function code 0x%02X", ErrInvalidRequest, info.Code)
+	}
+
+	functionsMu.Lock()
+	defer functionsMu.Unlock()
+
+	if _, ok := functions[info.Code]; ok {
+		return fmt.Errorf("%w: function code 0x%02X already registered", ErrInvalidRequest, info.Code)
+	}
+	functions[info.Code] = info
+	return nil
+}
+
+// LookupFunction returns the description of a function code
+func LookupFunction(code byte) (FunctionInfo, bool) {
+	functionsMu.RLock()
+	defer functionsMu.RUnlock()
+
+	info, ok := functions[code&0x7F]
+	return info, ok
+}
+
+// FunctionName returns the name of a function code, or its number if it
+// is not registered
+func FunctionName(code byte) string {
+	info, ok := LookupFunction(code)
+	if !ok {
+		return fmt.Sprintf("function 0x%02X", code&0x7F)
+	}
+	return info.Name
+}
+
+// responseLength returns the length of the response PDU starting with
+// pdu, exceptions included
+func responseLength(pdu []byte) (int, bool) {
+	if len(pdu) < 1 {
+		return 0, false
+	}
+	if pdu[0]&0x80 != 0 {
+		return 2, true
+	}
+
+	info, ok := LookupFunction(pdu[0])
+	if !ok || info.ResponseLength == nil {
+		return 0, false
+	}
+	return info.ResponseLength(pdu)
+}
@@ -0,0 +1,722 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// GenericClient implements the Modbus function codes once for every
+// transport. It builds request PDUs, sends them through a Transporter,
+// retrying them according to the retry policy, and decodes the responses.
+// The TCP, RTU, ASCII and UDP clients are generic clients over their own
+// transport.
+type GenericClient struct {
+	transport Transporter
+
+	mu            sync.Mutex
+	names         NameResolver
+	retry         *RetryPolicy
+	latency       *LatencyStats
+	oneBased      map[byte]bool
+	readFallback  map[byte]bool
+	writeFallback map[byte]bool
+}
+
+// NewGenericClient creates a client sending requests through transport
+func NewGenericClient(transport Transporter) *GenericClient {
+	return &GenericClient{
+		transport: transport,
+	}
+}
+
+// Transport returns the transport of the client
+func (c *GenericClient) Transport() Transporter {
+	return c.transport
+}
+
+// Connect connects the transport
+func (c *GenericClient) Connect() error {
+	return c.transport.Connect()
+}
+
+// Close closes the transport
+func (c *GenericClient) Close() error {
+	return c.transport.Close()
+}
+
+// SetTimeout sets the response timeout of the transport
+func (c *GenericClient) SetTimeout(timeout time.Duration) {
+	c.transport.SetTimeout(timeout)
+}
+
+// SetOneBased makes the addresses given for a device data-model addresses,
+// starting at 1, translated to protocol addresses starting at 0 on the
+// wire. Address 0 is then invalid for the device.
+func (c *GenericClient) SetOneBased(slaveID byte, oneBased bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.oneBased == nil {
+		c.oneBased = make(map[byte]bool)
+	}
+	c.oneBased[slaveID] = oneBased
+}
+
+// protocolAddress translates an address given for a device to the
+// address sent on the wire
+func (c *GenericClient) protocolAddress(slaveID byte, address uint16) (uint16, error) {
+	c.mu.Lock()
+	oneBased := c.oneBased[slaveID]
+	c.mu.Unlock()
+	return toProtocolAddress(oneBased, address)
+}
+
+// SetReadFallback makes reads from a device that answers IllegalFunction
+// retry on the other table of the same kind: input registers for holding
+// registers, discrete inputs for coils, and back
+func (c *GenericClient) SetReadFallback(slaveID byte, enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readFallback == nil {
+		c.readFallback = make(map[byte]bool)
+	}
+	c.readFallback[slaveID] = enabled
+}
+
+// sendReadRequest sends a read request, applying the read fallback
+func (c *GenericClient) sendReadRequest(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	response, err := c.sendRequest(ctx, slaveID, pdu)
+
+	c.mu.Lock()
+	fallback := c.readFallback[slaveID]
+	c.mu.Unlock()
+
+	alternate, ok := alternateReadFunction(pdu.FunctionCode)
+	if ok && fallback && errors.Is(err, ErrIllegalFunction) {
+		return c.sendRequest(ctx, slaveID, &PDU{
+			FunctionCode: alternate,
+			Data:         pdu.Data,
+		})
+	}
+	return response, err
+}
+
+// SetWriteFallback makes multiple coils or registers writes to a device
+// that answers IllegalFunction degrade to a sequence of single writes
+func (c *GenericClient) SetWriteFallback(slaveID byte, enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writeFallback == nil {
+		c.writeFallback = make(map[byte]bool)
+	}
+	c.writeFallback[slaveID] = enabled
+}
+
+// useWriteFallback reports whether a failed multiple write should be
+// retried as single writes
+func (c *GenericClient) useWriteFallback(slaveID byte, err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.writeFallback[slaveID] && errors.Is(err, ErrIllegalFunction)
+}
+
+// SetRetryPolicy sets how failed requests are retried, nil sends every
+// request once. WithRetryPolicy overrides it for a single call.
+func (c *GenericClient) SetRetryPolicy(policy *RetryPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retry = policy
+}
+
+// SetLatencyStats records the latency of every request attempt in stats,
+// nil stops recording
+func (c *GenericClient) SetLatencyStats(stats *LatencyStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.latency = stats
+}
+
+// SetNameResolver names devices in request errors, which are then
+// returned as *DeviceError. nil leaves errors unnamed.
+func (c *GenericClient) SetNameResolver(names NameResolver) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.names = names
+}
+
+// sendRequest sends a request, retrying it according to the retry policy
+// and naming the device in errors
+func (c *GenericClient) sendRequest(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	c.mu.Lock()
+	policy := retryPolicyFor(ctx, c.retry)
+	latency := c.latency
+	names := c.names
+	c.mu.Unlock()
+
+	response, err := policy.do(ctx, func() ([]byte, error) {
+		return latency.timed(c.transport.Endpoint(), slaveID, pdu.FunctionCode, func() ([]byte, error) {
+			return c.transport.Send(ctx, slaveID, pdu)
+		})
+	})
+	if err != nil {
+		return nil, nameError(names, c.transport.Endpoint(), slaveID, err)
+	}
+	return response, nil
+}
+
+// ReadCoils reads coil status
+func (c *GenericClient) ReadCoils(slaveID byte, address uint16, quantity uint16) ([]bool, error) {
+	return c.ReadCoilsContext(context.Background(), slaveID, address, quantity)
+}
+
+// ReadCoilsContext reads coil status, giving up when ctx is done
+func (c *GenericClient) ReadCoilsContext(ctx context.Context, slaveID byte, address uint16, quantity uint16) ([]bool, error) {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateRange(start, int(quantity), maxReadBits)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint16(data[0:2], start)
+	binary.BigEndian.PutUint16(data[2:4], quantity)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeReadCoils,
+		Data:         data,
+	}
+
+	response, err := c.sendReadRequest(ctx, slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) < 1 {
+		return nil, ErrInvalidResponse
+	}
+
+	return bytesToBools(response[1:], quantity), nil
+}
+
+// ReadDiscreteInputs reads discrete input status
+func (c *GenericClient) ReadDiscreteInputs(slaveID byte, address uint16, quantity uint16) ([]bool, error) {
+	return c.ReadDiscreteInputsContext(context.Background(), slaveID, address, quantity)
+}
+
+// ReadDiscreteInputsContext reads discrete input status, giving up when ctx is done
+func (c *GenericClient) ReadDiscreteInputsContext(ctx context.Context, slaveID byte, address uint16, quantity uint16) ([]bool, error) {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateRange(start, int(quantity), maxReadBits)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint16(data[0:2], start)
+	binary.BigEndian.PutUint16(data[2:4], quantity)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeReadDiscreteInputs,
+		Data:         data,
+	}
+
+	response, err := c.sendReadRequest(ctx, slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) < 1 {
+		return nil, ErrInvalidResponse
+	}
+
+	return bytesToBools(response[1:], quantity), nil
+}
+
+// ReadHoldingRegisters reads holding registers
+func (c *GenericClient) ReadHoldingRegisters(slaveID byte, address uint16, quantity uint16) ([]uint16, error) {
+	return c.ReadHoldingRegistersContext(context.Background(), slaveID, address, quantity)
+}
+
+// ReadHoldingRegistersContext reads holding registers, giving up when ctx is done
+func (c *GenericClient) ReadHoldingRegistersContext(ctx context.Context, slaveID byte, address uint16, quantity uint16) ([]uint16, error) {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateRange(start, int(quantity), maxReadRegisters)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint16(data[0:2], start)
+	binary.BigEndian.PutUint16(data[2:4], quantity)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeReadHoldingRegisters,
+		Data:         data,
+	}
+
+	response, err := c.sendReadRequest(ctx, slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) < 1 {
+		return nil, ErrInvalidResponse
+	}
+
+	return bytesToUint16s(response[1:]), nil
+}
+
+// ReadHoldingRegistersMeta reads holding registers and returns when and
+// how they were read
+func (c *GenericClient) ReadHoldingRegistersMeta(slaveID byte, address uint16, quantity uint16) ([]uint16, ReadMeta, error) {
+	return c.ReadHoldingRegistersMetaContext(context.Background(), slaveID, address, quantity)
+}
+
+// ReadHoldingRegistersMetaContext reads holding registers and returns when
+// and how they were read, giving up when ctx is done
+func (c *GenericClient) ReadHoldingRegistersMetaContext(ctx context.Context, slaveID byte, address uint16, quantity uint16) ([]uint16, ReadMeta, error) {
+	var meta ReadMeta
+	values, err := c.ReadHoldingRegistersContext(withReadMeta(ctx, &meta), slaveID, address, quantity)
+	return values, meta, err
+}
+
+// ReadInputRegisters reads input registers
+func (c *GenericClient) ReadInputRegisters(slaveID byte, address uint16, quantity uint16) ([]uint16, error) {
+	return c.ReadInputRegistersContext(context.Background(), slaveID, address, quantity)
+}
+
+// ReadInputRegistersContext reads input registers, giving up when ctx is done
+func (c *GenericClient) ReadInputRegistersContext(ctx context.Context, slaveID byte, address uint16, quantity uint16) ([]uint16, error) {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateRange(start, int(quantity), maxReadRegisters)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint16(data[0:2], start)
+	binary.BigEndian.PutUint16(data[2:4], quantity)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeReadInputRegisters,
+		Data:         data,
+	}
+
+	response, err := c.sendReadRequest(ctx, slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) < 1 {
+		return nil, ErrInvalidResponse
+	}
+
+	return bytesToUint16s(response[1:]), nil
+}
+
+// WriteSingleCoil writes a single coil
+func (c *GenericClient) WriteSingleCoil(slaveID byte, address uint16, value bool) error {
+	return c.WriteSingleCoilContext(context.Background(), slaveID, address, value)
+}
+
+// WriteSingleCoilContext writes a single coil, giving up when ctx is done
+func (c *GenericClient) WriteSingleCoilContext(ctx context.Context, slaveID byte, address uint16, value bool) error {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return err
+	}
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint16(data[0:2], start)
+	if value {
+		binary.BigEndian.PutUint16(data[2:4], 0xFF00)
+	} else {
+		binary.BigEndian.PutUint16(data[2:4], 0x0000)
+	}
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeWriteSingleCoil,
+		Data:         data,
+	}
+
+	_, err = c.sendRequest(ctx, slaveID, pdu)
+	return err
+}
+
+// WriteSingleRegister writes a single register
+func (c *GenericClient) WriteSingleRegister(slaveID byte, address uint16, value uint16) error {
+	return c.WriteSingleRegisterContext(context.Background(), slaveID, address, value)
+}
+
+// WriteSingleRegisterContext writes a single register, giving up when ctx is done
+func (c *GenericClient) WriteSingleRegisterContext(ctx context.Context, slaveID byte, address uint16, value uint16) error {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return err
+	}
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint16(data[0:2], start)
+	binary.BigEndian.PutUint16(data[2:4], value)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeWriteSingleRegister,
+		Data:         data,
+	}
+
+	_, err = c.sendRequest(ctx, slaveID, pdu)
+	return err
+}
+
+// WriteMultipleCoils writes multiple coils
+func (c *GenericClient) WriteMultipleCoils(slaveID byte, address uint16, values []bool) error {
+	return c.WriteMultipleCoilsContext(context.Background(), slaveID, address, values)
+}
+
+// WriteMultipleCoilsContext writes multiple coils, giving up when ctx is done
+func (c *GenericClient) WriteMultipleCoilsContext(ctx context.Context, slaveID byte, address uint16, values []bool) error {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return err
+	}
+	err = ValidateRange(start, len(values), maxWriteBits)
+	if err != nil {
+		return err
+	}
+
+	byteCount := (len(values) + 7) / 8
+	data := make([]byte, 5+byteCount)
+	binary.BigEndian.PutUint16(data[0:2], start)
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(values)))
+	data[4] = byte(byteCount)
+
+	coilBytes := boolsToBytes(values)
+	copy(data[5:], coilBytes)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeWriteMultipleCoils,
+		Data:         data,
+	}
+
+	_, err = c.sendRequest(ctx, slaveID, pdu)
+	if c.useWriteFallback(slaveID, err) {
+		for i, value := range values {
+			err = c.WriteSingleCoilContext(ctx, slaveID, address+uint16(i), value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+// WriteMultipleRegisters writes multiple registers
+func (c *GenericClient) WriteMultipleRegisters(slaveID byte, address uint16, values []uint16) error {
+	return c.WriteMultipleRegistersContext(context.Background(), slaveID, address, values)
+}
+
+// WriteMultipleRegistersContext writes multiple registers, giving up when ctx is done
+func (c *GenericClient) WriteMultipleRegistersContext(ctx context.Context, slaveID byte, address uint16, values []uint16) error {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return err
+	}
+	err = ValidateRange(start, len(values), maxWriteRegisters)
+	if err != nil {
+		return err
+	}
+
+	data := make([]byte, 5+len(values)*2)
+	binary.BigEndian.PutUint16(data[0:2], start)
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(values)))
+	data[4] = byte(len(values) * 2)
+
+	regBytes := uint16sToBytes(values)
+	copy(data[5:], regBytes)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeWriteMultipleRegisters,
+		Data:         data,
+	}
+
+	_, err = c.sendRequest(ctx, slaveID, pdu)
+	if c.useWriteFallback(slaveID, err) {
+		for i, value := range values {
+			err = c.WriteSingleRegisterContext(ctx, slaveID, address+uint16(i), value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+// MaskWriteRegister modifies the bits of a holding register in one
+// transaction: the register becomes (current AND andMask) OR (orMask AND
+// NOT andMask)
+func (c *GenericClient) MaskWriteRegister(slaveID byte, address uint16, andMask uint16, orMask uint16) error {
+	return c.MaskWriteRegisterContext(context.Background(), slaveID, address, andMask, orMask)
+}
+
+// MaskWriteRegisterContext modifies the bits of a holding register in one
+// transaction, giving up when ctx is done
+func (c *GenericClient) MaskWriteRegisterContext(ctx context.Context, slaveID byte, address uint16, andMask uint16, orMask uint16) error {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return err
+	}
+
+	data := make([]byte, 6)
+	binary.BigEndian.PutUint16(data[0:2], start)
+	binary.BigEndian.PutUint16(data[2:4], andMask)
+	binary.BigEndian.PutUint16(data[4:6], orMask)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeMaskWriteRegister,
+		Data:         data,
+	}
+
+	_, err = c.sendRequest(ctx, slaveID, pdu)
+	return err
+}
+
+// ReadWriteMultipleRegisters writes values starting at writeAddress, then
+// reads quantity registers starting at readAddress, in one transaction
+func (c *GenericClient) ReadWriteMultipleRegisters(slaveID byte, readAddress uint16, quantity uint16, writeAddress uint16, values []uint16) ([]uint16, error) {
+	return c.ReadWriteMultipleRegistersContext(context.Background(), slaveID, readAddress, quantity, writeAddress, values)
+}
+
+// ReadWriteMultipleRegistersContext writes values starting at writeAddress,
+// then reads quantity registers starting at readAddress, in one
+// transaction, giving up when ctx is done
+func (c *GenericClient) ReadWriteMultipleRegistersContext(ctx context.Context, slaveID byte, readAddress uint16, quantity uint16, writeAddress uint16, values []uint16) ([]uint16, error) {
+	readStart, err := c.protocolAddress(slaveID, readAddress)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateRange(readStart, int(quantity), maxReadRegisters)
+	if err != nil {
+		return nil, err
+	}
+	writeStart, err := c.protocolAddress(slaveID, writeAddress)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateRange(writeStart, len(values), maxReadWriteRegisters)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 9+len(values)*2)
+	binary.BigEndian.PutUint16(data[0:2], readStart)
+	binary.BigEndian.PutUint16(data[2:4], quantity)
+	binary.BigEndian.PutUint16(data[4:6], writeStart)
+	binary.BigEndian.PutUint16(data[6:8], uint16(len(values)))
+	data[8] = byte(len(values) * 2)
+
+	regBytes := uint16sToBytes(values)
+	copy(data[9:], regBytes)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeReadWriteMultipleRegisters,
+		Data:         data,
+	}
+
+	response, err := c.sendRequest(ctx, slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) < 1 || int(response[0]) != int(quantity)*2 || len(response) < 1+int(quantity)*2 {
+		return nil, ErrInvalidResponse
+	}
+
+	return bytesToUint16s(response[1 : 1+int(quantity)*2]), nil
+}
+
+// ReadFIFOQueue reads the content of the FIFO queue of registers whose
+// count register is at address, at most 31 values
+func (c *GenericClient) ReadFIFOQueue(slaveID byte, address uint16) ([]uint16, error) {
+	return c.ReadFIFOQueueContext(context.Background(), slaveID, address)
+}
+
+// ReadFIFOQueueContext reads the content of the FIFO queue of registers
+// whose count register is at address, giving up when ctx is done
+func (c *GenericClient) ReadFIFOQueueContext(ctx context.Context, slaveID byte, address uint16) ([]uint16, error) {
+	start, err := c.protocolAddress(slaveID, address)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data[0:2], start)
+
+	pdu := &PDU{
+		FunctionCode: FuncCodeReadFIFOQueue,
+		Data:         data,
+	}
+
+	response, err := c.sendRequest(ctx, slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	// Byte count and FIFO count, then the values
+	if len(response) < 4 {
+		return nil, ErrInvalidResponse
+	}
+	byteCount := int(binary.BigEndian.Uint16(response[0:2]))
+	count := int(binary.BigEndian.Uint16(response[2:4]))
+	if count > 31 || byteCount != 2+count*2 || len(response) < 4+count*2 {
+		return nil, ErrInvalidResponse
+	}
+
+	return bytesToUint16s(response[4 : 4+count*2]), nil
+}
+
+// ReadDeviceIdentification reads the identification objects of a device
+// selected by code, following the continuation of long answers
+func (c *GenericClient) ReadDeviceIdentification(slaveID byte, code DeviceIDCode) (*DeviceIdentification, error) {
+	return c.ReadDeviceIdentificationContext(context.Background(), slaveID, code)
+}
+
+// ReadDeviceIdentificationContext reads the identification objects of a
+// device selected by code, giving up when ctx is done
+func (c *GenericClient) ReadDeviceIdentificationContext(ctx context.Context, slaveID byte, code DeviceIDCode) (*DeviceIdentification, error) {
+	return readDeviceIdentification(ctx, c.sendRequest, slaveID, code)
+}
+
+// ReturnQueryData sends data to a device in a diagnostics loopback and
+// checks it is echoed unchanged, which verifies the wiring to the device
+func (c *GenericClient) ReturnQueryData(slaveID byte, data []byte) error {
+	return c.ReturnQueryDataContext(context.Background(), slaveID, data)
+}
+
+// ReturnQueryDataContext sends data to a device in a diagnostics loopback
+// and checks it is echoed unchanged, giving up when ctx is done
+func (c *GenericClient) ReturnQueryDataContext(ctx context.Context, slaveID byte, data []byte) error {
+	return returnQueryData(ctx, c.sendRequest, slaveID, data)
+}
+
+// RestartCommunications restarts the serial line of a device and takes it
+// out of listen only mode, clearing its event log if clearLog is set
+func (c *GenericClient) RestartCommunications(slaveID byte, clearLog bool) error {
+	return c.RestartCommunicationsContext(context.Background(), slaveID, clearLog)
+}
+
+// RestartCommunicationsContext restarts the serial line of a device,
+// giving up when ctx is done
+func (c *GenericClient) RestartCommunicationsContext(ctx context.Context, slaveID byte, clearLog bool) error {
+	return restartCommunications(ctx, c.sendRequest, slaveID, clearLog)
+}
+
+// ReadDiagnosticRegister returns the diagnostic register of a device
+func (c *GenericClient) ReadDiagnosticRegister(slaveID byte) (uint16, error) {
+	return c.ReadDiagnosticRegisterContext(context.Background(), slaveID)
+}
+
+// ReadDiagnosticRegisterContext returns the diagnostic register of a
+// device, giving up when ctx is done
+func (c *GenericClient) ReadDiagnosticRegisterContext(ctx context.Context, slaveID byte) (uint16, error) {
+	return diagnosticWord(ctx, c.sendRequest, slaveID, DiagReturnDiagnosticRegister)
+}
+
+// ClearCounters clears the diagnostic counters and register of a device
+func (c *GenericClient) ClearCounters(slaveID byte) error {
+	return c.ClearCountersContext(context.Background(), slaveID)
+}
+
+// ClearCountersContext clears the diagnostic counters and register of a
+// device, giving up when ctx is done
+func (c *GenericClient) ClearCountersContext(ctx context.Context, slaveID byte) error {
+	_, err := diagnosticWord(ctx, c.sendRequest, slaveID, DiagClearCountersAndRegister)
+	return err
+}
+
+// ReadDiagnosticCounter returns a diagnostic counter of a device
+func (c *GenericClient) ReadDiagnosticCounter(slaveID byte, counter DiagnosticCounter) (uint16, error) {
+	return c.ReadDiagnosticCounterContext(context.Background(), slaveID, counter)
+}
+
+// ReadDiagnosticCounterContext returns a diagnostic counter of a device,
+// giving up when ctx is done
+func (c *GenericClient) ReadDiagnosticCounterContext(ctx context.Context, slaveID byte, counter DiagnosticCounter) (uint16, error) {
+	if counter < CounterBusMessages || counter > CounterBusCharOverruns {
+		return 0, ErrInvalidQuantity
+	}
+	return diagnosticWord(ctx, c.sendRequest, slaveID, uint16(counter))
+}
+
+// GetCommEventCounter returns the status word and the event counter of a
+// serial device. The counter is incremented by every successful message
+// except exceptions and event counter requests.
+func (c *GenericClient) GetCommEventCounter(slaveID byte) (status uint16, count uint16, err error) {
+	return c.GetCommEventCounterContext(context.Background(), slaveID)
+}
+
+// GetCommEventCounterContext returns the status word and the event counter
+// of a serial device, giving up when ctx is done
+func (c *GenericClient) GetCommEventCounterContext(ctx context.Context, slaveID byte) (status uint16, count uint16, err error) {
+	pdu := &PDU{
+		FunctionCode: FuncCodeGetCommEventCounter,
+	}
+
+	response, err := c.sendRequest(ctx, slaveID, pdu)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(response) < 4 {
+		return 0, 0, ErrInvalidResponse
+	}
+
+	return binary.BigEndian.Uint16(response[0:2]), binary.BigEndian.Uint16(response[2:4]), nil
+}
+
+// SendRawPDU sends a request PDU with any function code, such as a vendor
+// one, and returns the response PDU. Exception responses are returned as
+// *ModbusError.
+func (c *GenericClient) SendRawPDU(slaveID byte, pdu *PDU) (*PDU, error) {
+	return c.SendRawPDUContext(context.Background(), slaveID, pdu)
+}
+
+// SendRawPDUContext sends a request PDU with any function code and returns
+// the response PDU, giving up when ctx is done
+func (c *GenericClient) SendRawPDUContext(ctx context.Context, slaveID byte, pdu *PDU) (*PDU, error) {
+	if pdu.FunctionCode == 0 || pdu.FunctionCode&0x80 != 0 {
+		return nil, fmt.Errorf("%w: function code 0x%02X", ErrInvalidRequest, pdu.FunctionCode)
+	}
+	if len(pdu.Data) > 252 {
+		return nil, fmt.Errorf("%w: %d data bytes", ErrInvalidRequest, len(pdu.Data))
+	}
+
+	response, err := c.sendRequest(ctx, slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PDU{
+		FunctionCode: pdu.FunctionCode,
+		Data:         response,
+	}, nil
+}
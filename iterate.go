@@ -0,0 +1,68 @@
+package modbus
+
+import (
+	"iter"
+)
+
+// IterRegisters ranges over quantity holding registers starting at
+// address, yielding each address with its value. Registers are read in
+// chunks of the largest request as the loop advances, so huge ranges are
+// never held in memory. The returned function reports the error that
+// stopped the iteration early, if any.
+//
+//	registers, errFn := IterRegisters(client, 1, 0, 10000)
+//	for address, value := range registers {
+//		...
+//	}
+//	if err := errFn(); err != nil {
+//		...
+//	}
+func IterRegisters(client Client, slaveID byte, address uint16, quantity int) (iter.Seq2[uint16, uint16], func() error) {
+	var err error
+
+	registers := func(yield func(uint16, uint16) bool) {
+		err = nil
+		if quantity <= 0 {
+			err = ErrInvalidQuantity
+			return
+		}
+		if int(address)+quantity > 0x10000 {
+			err = ErrInvalidAddress
+			return
+		}
+
+		for offset, chunk := range chunks(quantity, maxReadRegisters) {
+			start := address + uint16(offset)
+
+			var values []uint16
+			values, err = client.ReadHoldingRegisters(slaveID, start, uint16(chunk))
+			if err != nil {
+				return
+			}
+			if len(values) < chunk {
+				err = ErrInvalidResponse
+				return
+			}
+
+			for i, value := range values[:chunk] {
+				if !yield(start+uint16(i), value) {
+					return
+				}
+			}
+		}
+	}
+
+	return registers, func() error { return err }
+}
+
+// chunks splits quantity items into requests of at most size items,
+// yielding the offset and length of each
+func chunks(quantity int, size int) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for offset := 0; offset < quantity; offset += size {
+			if !yield(offset, min(quantity-offset, size)) {
+				return
+			}
+		}
+	}
+}
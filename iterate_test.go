@@ -0,0 +1,27 @@
+package modbus
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestChunks(t *testing.T) {
+	tests := []struct {
+		quantity, size int
+		want           [][2]int
+	}{
+		{0, 125, nil},
+		{1, 125, [][2]int{{0, 1}}},
+		{125, 125, [][2]int{{0, 125}}},
+		{300, 125, [][2]int{{0, 125}, {125, 125}, {250, 50}}},
+	}
+	for _, tt := range tests {
+		var got [][2]int
+		for offset, n := range chunks(tt.quantity, tt.size) {
+			got = append(got, [2]int{offset, n})
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("chunks(%d, %d) = %v, want %v", tt.quantity, tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package modbus
+
+import (
+	"context"
+	"time"
+)
+
+// ReadMeta describes how a read was performed, for applications that need
+// precise sampling times
+type ReadMeta struct {
+	// RequestTime is when the request answered was sent
+	RequestTime time.Time
+	// ResponseTime is when its response was received
+	ResponseTime time.Time
+	// TransactionID is the MBAP transaction ID of the request, 0 on
+	// serial lines
+	TransactionID uint16
+	// Retries is the number of requests sent before the one answered
+	Retries int
+}
+
+type readMetaKey struct{}
+
+// withReadMeta returns a context recording the requests made with it in meta
+func withReadMeta(ctx context.Context, meta *ReadMeta) context.Context {
+	return context.WithValue(ctx, readMetaKey{}, meta)
+}
+
+// readMetaFrom returns the metadata recorded for ctx, nil if none
+func readMetaFrom(ctx context.Context) *ReadMeta {
+	meta, _ := ctx.Value(readMetaKey{}).(*ReadMeta)
+	return meta
+}
+
+// sent records a request being sent, any earlier one becomes a retry
+func (m *ReadMeta) sent(transID uint16) {
+	if m == nil {
+		return
+	}
+	if !m.RequestTime.IsZero() {
+		m.Retries++
+	}
+	m.RequestTime = time.Now()
+	m.ResponseTime = time.Time{}
+	m.TransactionID = transID
+}
+
+// received records the response to the last request
+func (m *ReadMeta) received(t time.Time) {
+	if m == nil {
+		return
+	}
+	m.ResponseTime = t
+}
@@ -0,0 +1,212 @@
+package modbustest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SamyFrancelet/modbus"
+)
+
+// Step is one expected request and its canned response
+type Step struct {
+	UnitID   byte
+	Request  []byte // expected request PDU
+	Response []byte // response PDU, nil sends no response
+	// Delay is waited before sending the response
+	Delay time.Duration
+	// Within is the maximum time allowed between the previous response
+	// (or loading the scenario) and this request, zero means no limit
+	Within time.Duration
+}
+
+// Scenario is an ordered sequence of steps played by the fake server
+type Scenario struct {
+	Steps []Step
+}
+
+// ParseScenario reads a scenario in text form. Each non-empty line is a
+// step made of the unit ID, the request PDU and the response PDU in hex,
+// followed by optional delay and within durations:
+//
+//	# unit request => response [delay=D] [within=D]
+//	1 03 0000 0002 => 03 04 0001 0002
+//	1 06 0010 0001 => 86 06 delay=10ms
+//	1 06 0010 0001 => 06 0010 0001 within=1s
+//	1 05 0000 FF00 => none
+//
+// Bytes may be grouped freely, whitespace between hex digits is ignored.
+// A response of "none" sends nothing back. Text after '#' is a comment.
+func ParseScenario(r io.Reader) (*Scenario, error) {
+	scenario := &Scenario{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		step, err := parseStep(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		scenario.Steps = append(scenario.Steps, step)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return scenario, nil
+}
+
+func parseStep(line string) (Step, error) {
+	var step Step
+
+	request, response, ok := strings.Cut(line, "=>")
+	if !ok {
+		return step, fmt.Errorf("missing '=>'")
+	}
+
+	fields := strings.Fields(request)
+	if len(fields) < 2 {
+		return step, fmt.Errorf("expected unit ID and request")
+	}
+
+	unitID, err := strconv.ParseUint(fields[0], 0, 8)
+	if err != nil {
+		return step, fmt.Errorf("invalid unit ID: %w", err)
+	}
+	step.UnitID = byte(unitID)
+
+	step.Request, err = hex.DecodeString(strings.Join(fields[1:], ""))
+	if err != nil {
+		return step, fmt.Errorf("invalid request: %w", err)
+	}
+
+	var responseHex []string
+	for _, field := range strings.Fields(response) {
+		key, value, isOption := strings.Cut(field, "=")
+		if !isOption {
+			responseHex = append(responseHex, field)
+			continue
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return step, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		switch key {
+		case "delay":
+			step.Delay = d
+		case "within":
+			step.Within = d
+		default:
+			return step, fmt.Errorf("unknown option %q", key)
+		}
+	}
+
+	if len(responseHex) == 1 && responseHex[0] == "none" {
+		return step, nil
+	}
+
+	step.Response, err = hex.DecodeString(strings.Join(responseHex, ""))
+	if err != nil {
+		return step, fmt.Errorf("invalid response: %w", err)
+	}
+	if len(step.Response) == 0 {
+		return step, fmt.Errorf("missing response")
+	}
+	return step, nil
+}
+
+// LoadScenario makes the server answer requests from the scenario instead
+// of its data tables. Requests that do not match the next step are
+// answered with an IllegalFunction exception and reported as test errors
+// by AssertScenarioDone, or when the test ends.
+func (s *Server) LoadScenario(scenario *Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scenario = scenario
+	s.scenarioStep = 0
+	s.scenarioMark = time.Now()
+}
+
+// AssertScenarioDone checks that every step of the loaded scenario was
+// played and that every request matched its step
+func (s *Server) AssertScenarioDone() {
+	s.t.Helper()
+	s.reportFailures()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scenario == nil {
+		return
+	}
+	if remaining := len(s.scenario.Steps) - s.scenarioStep; remaining > 0 {
+		s.t.Errorf("modbustest: scenario incomplete, %d of %d steps remaining",
+			remaining, len(s.scenario.Steps))
+	}
+}
+
+// fail records a failure of the scenario, the caller must hold s.mu
+func (s *Server) fail(format string, args ...any) {
+	s.failures = append(s.failures, fmt.Sprintf(format, args...))
+}
+
+// reportFailures reports the failures recorded so far as test errors, it
+// must be called from the test goroutine
+func (s *Server) reportFailures() {
+	s.t.Helper()
+
+	s.mu.Lock()
+	failures := s.failures
+	s.failures = nil
+	s.mu.Unlock()
+
+	for _, failure := range failures {
+		s.t.Error(failure)
+	}
+}
+
+// playScenario matches a request against the next scenario step and
+// returns the response to send and the delay before sending it,
+// the caller must hold s.mu
+func (s *Server) playScenario(unitID byte, pdu []byte) ([]byte, time.Duration) {
+	if s.scenarioStep >= len(s.scenario.Steps) {
+		s.fail("modbustest: unexpected request after scenario end: unit=%d pdu=% X", unitID, pdu)
+		return exception(pdu[0], modbus.ExceptionIllegalFunction), 0
+	}
+
+	index := s.scenarioStep
+	step := s.scenario.Steps[index]
+	s.scenarioStep++
+
+	if step.UnitID != unitID || !bytes.Equal(step.Request, pdu) {
+		s.fail("modbustest: step %d: expected unit=%d pdu=% X, got unit=%d pdu=% X",
+			index, step.UnitID, step.Request, unitID, pdu)
+		return exception(pdu[0], modbus.ExceptionIllegalFunction), 0
+	}
+
+	if elapsed := time.Since(s.scenarioMark); step.Within > 0 && elapsed > step.Within {
+		s.fail("modbustest: step %d: request arrived after %v, expected within %v",
+			index, elapsed, step.Within)
+	}
+
+	// The next step is timed from when this response is sent
+	s.scenarioMark = time.Now().Add(step.Delay)
+	return step.Response, step.Delay
+}
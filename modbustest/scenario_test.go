@@ -0,0 +1,108 @@
+package modbustest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SamyFrancelet/modbus"
+)
+
+func TestParseScenario(t *testing.T) {
+	scenario, err := ParseScenario(strings.NewReader(`
+# unit request => response [delay=D] [within=D]
+1 03 0000 0002 => 03 04 0001 0002
+0x02 06 0010 0001 => 86 06 delay=10ms # exception
+1 06 0010 0001 => 06 0010 0001 within=1s
+
+1 05 0000 FF00 => none
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Step{
+		{UnitID: 1, Request: []byte{3, 0, 0, 0, 2}, Response: []byte{3, 4, 0, 1, 0, 2}},
+		{UnitID: 2, Request: []byte{6, 0, 0x10, 0, 1}, Response: []byte{0x86, 6}, Delay: 10 * time.Millisecond},
+		{UnitID: 1, Request: []byte{6, 0, 0x10, 0, 1}, Response: []byte{6, 0, 0x10, 0, 1}, Within: time.Second},
+		{UnitID: 1, Request: []byte{5, 0, 0, 0xFF, 0}},
+	}
+	if !reflect.DeepEqual(scenario.Steps, want) {
+		t.Errorf("ParseScenario() = %+v, want %+v", scenario.Steps, want)
+	}
+}
+
+func TestParseScenarioInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"missing arrow", "1 03 0000 0001", "line 1: missing '=>'"},
+		{"missing request", "1 => 03 02 0000", "line 1: expected unit ID and request"},
+		{"unit ID out of range", "256 03 0000 0001 => 03 02 0000", "line 1: invalid unit ID"},
+		{"odd request digits", "1 03 000 0001 => 03 02 0000", "line 1: invalid request"},
+		{"invalid response", "1 03 0000 0001 => 03 0G", "line 1: invalid response"},
+		{"missing response", "1 03 0000 0001 => delay=1ms", "line 1: missing response"},
+		{"invalid duration", "1 03 0000 0001 => 03 02 0000 delay=soon", "line 1: invalid delay"},
+		{"unknown option", "1 03 0000 0001 => 03 02 0000 after=1s", `line 1: unknown option "after"`},
+		{"line number", "# comment\n\n1 03 0000 0001 => none\n1 03", "line 4: missing '=>'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScenario(strings.NewReader(tt.input))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("ParseScenario() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// recordingTB records the errors reported through it
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Error(args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprint(args...))
+}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+// Mismatched requests are reported from the test goroutine, not from the
+// server goroutine answering them
+func TestServerScenarioMismatch(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	s := NewServer(tb)
+	scenario, err := ParseScenario(strings.NewReader("1 03 0000 0001 => 03 02 0007\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.LoadScenario(scenario)
+
+	c := modbus.NewTCPClient(s.Addr())
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_, err = c.ReadHoldingRegisters(1, 1, 1)
+	if !errors.Is(err, modbus.ErrIllegalFunction) {
+		t.Fatalf("ReadHoldingRegisters() = %v, want %v", err, modbus.ErrIllegalFunction)
+	}
+	if len(tb.errors) != 0 {
+		t.Fatalf("errors reported by the server goroutine: %v", tb.errors)
+	}
+
+	s.AssertScenarioDone()
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "step 0: expected unit=1") {
+		t.Errorf("AssertScenarioDone() reported %q, want the mismatch", tb.errors)
+	}
+}
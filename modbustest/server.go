@@ -0,0 +1,521 @@
+// Package modbustest provides fake Modbus TCP and RTU servers with
+// assertion helpers for end-to-end client tests.
+package modbustest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SamyFrancelet/modbus"
+)
+
+// Write records a single coil or register written by a client.
+// Multiple writes are recorded as one Write per address, mask writes
+// with the resulting value.
+type Write struct {
+	UnitID       byte
+	FunctionCode byte
+	Address      uint16
+	Value        uint16 // 1 or 0 for coils
+}
+
+func (w Write) String() string {
+	return fmt.Sprintf("unit=%d function=0x%02X address=%d value=%d",
+		w.UnitID, w.FunctionCode, w.Address, w.Value)
+}
+
+// unit holds the data of one unit ID
+type unit struct {
+	coils            map[uint16]bool
+	discreteInputs   map[uint16]bool
+	holdingRegisters map[uint16]uint16
+	inputRegisters   map[uint16]uint16
+}
+
+// Server is a fake Modbus server listening on a loopback address, with
+// MBAP framing or RTU framing over TCP
+type Server struct {
+	t        testing.TB
+	listener net.Listener
+	rtu      bool
+	mu       sync.Mutex
+	units    map[byte]*unit
+	writes   []Write
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+
+	scenario     *Scenario
+	scenarioStep int
+	scenarioMark time.Time
+	// failures of the scenario, reported from the test goroutine as
+	// the server goroutines may outlive the test
+	failures []string
+}
+
+// NewServer starts a fake Modbus TCP server, it is closed when the test
+// ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	return newServer(t, false)
+}
+
+// NewRTUServer starts a fake Modbus RTU server exchanging RTU frames over
+// TCP, as a serial-to-Ethernet converter in raw mode, for testing
+// RTUOverTCPClient. It is closed when the test ends.
+func NewRTUServer(t testing.TB) *Server {
+	t.Helper()
+	return newServer(t, true)
+}
+
+func newServer(t testing.TB, rtu bool) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("modbustest: failed to listen: %v", err)
+	}
+
+	s := &Server{
+		t:        t,
+		listener: listener,
+		rtu:      rtu,
+		units:    make(map[byte]*unit),
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	t.Cleanup(func() {
+		s.Close()
+		s.reportFailures()
+	})
+	return s
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and closes all client connections
+func (s *Server) Close() {
+	s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// SetCoil sets the value of a coil
+func (s *Server) SetCoil(unitID byte, address uint16, value bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unit(unitID).coils[address] = value
+}
+
+// SetDiscreteInput sets the value of a discrete input
+func (s *Server) SetDiscreteInput(unitID byte, address uint16, value bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unit(unitID).discreteInputs[address] = value
+}
+
+// SetHoldingRegister sets the value of a holding register
+func (s *Server) SetHoldingRegister(unitID byte, address uint16, value uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unit(unitID).holdingRegisters[address] = value
+}
+
+// SetInputRegister sets the value of an input register
+func (s *Server) SetInputRegister(unitID byte, address uint16, value uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unit(unitID).inputRegisters[address] = value
+}
+
+// Coil returns the current value of a coil
+func (s *Server) Coil(unitID byte, address uint16) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unit(unitID).coils[address]
+}
+
+// HoldingRegister returns the current value of a holding register
+func (s *Server) HoldingRegister(unitID byte, address uint16) uint16 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unit(unitID).holdingRegisters[address]
+}
+
+// Writes returns all writes received so far, in order
+func (s *Server) Writes() []Write {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writes := make([]Write, len(s.writes))
+	copy(writes, s.writes)
+	return writes
+}
+
+// AssertReceivedWrite checks that value was written to the holding register
+// at address of the unit, by a single, multiple or mask register write
+func (s *Server) AssertReceivedWrite(unitID byte, address uint16, value uint16) {
+	s.t.Helper()
+	s.assertWrite(unitID, address, value, modbus.FuncCodeWriteSingleRegister,
+		modbus.FuncCodeWriteMultipleRegisters, modbus.FuncCodeMaskWriteRegister)
+}
+
+// AssertReceivedCoilWrite checks that value was written to the coil at
+// address of the unit, by a single or multiple coil write
+func (s *Server) AssertReceivedCoilWrite(unitID byte, address uint16, value bool) {
+	s.t.Helper()
+	var v uint16
+	if value {
+		v = 1
+	}
+	s.assertWrite(unitID, address, v, modbus.FuncCodeWriteSingleCoil, modbus.FuncCodeWriteMultipleCoils)
+}
+
+// AssertNoWrites checks that no write was received
+func (s *Server) AssertNoWrites() {
+	s.t.Helper()
+	if writes := s.Writes(); len(writes) > 0 {
+		s.t.Errorf("modbustest: expected no writes, received %v", writes)
+	}
+}
+
+func (s *Server) assertWrite(unitID byte, address uint16, value uint16, functionCodes ...byte) {
+	s.t.Helper()
+
+	writes := s.Writes()
+	for _, w := range writes {
+		if w.UnitID != unitID || w.Address != address || w.Value != value {
+			continue
+		}
+		for _, fc := range functionCodes {
+			if w.FunctionCode == fc {
+				return
+			}
+		}
+	}
+	s.t.Errorf("modbustest: no write of %d to unit %d address %d, received %v",
+		value, unitID, address, writes)
+}
+
+// unit returns the data of a unit ID, the caller must hold s.mu
+func (s *Server) unit(unitID byte) *unit {
+	u, ok := s.units[unitID]
+	if !ok {
+		u = &unit{
+			coils:            make(map[uint16]bool),
+			discreteInputs:   make(map[uint16]bool),
+			holdingRegisters: make(map[uint16]uint16),
+			inputRegisters:   make(map[uint16]uint16),
+		}
+		s.units[unitID] = u
+	}
+	return u
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	if s.rtu {
+		s.serveRTU(conn)
+	} else {
+		s.serveMBAP(conn)
+	}
+}
+
+// serveMBAP answers MBAP framed requests until the connection fails
+func (s *Server) serveMBAP(conn net.Conn) {
+	header := make([]byte, 7)
+	for {
+		_, err := io.ReadFull(conn, header)
+		if err != nil {
+			return
+		}
+
+		length := binary.BigEndian.Uint16(header[4:6])
+		if length < 2 {
+			return
+		}
+
+		pdu := make([]byte, length-1)
+		_, err = io.ReadFull(conn, pdu)
+		if err != nil {
+			return
+		}
+
+		response, delay := s.respond(header[6], pdu)
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		if response == nil {
+			continue
+		}
+
+		binary.BigEndian.PutUint16(header[4:6], uint16(1+len(response)))
+		_, err = conn.Write(append(header, response...))
+		if err != nil {
+			return
+		}
+	}
+}
+
+// serveRTU answers RTU framed requests until the connection fails.
+// Frames with an invalid CRC and broadcasts are not answered, as on a
+// serial bus.
+func (s *Server) serveRTU(conn net.Conn) {
+	for {
+		frame, err := readRTURequest(conn)
+		if err != nil {
+			return
+		}
+		if len(frame) < 4 || !modbus.CheckCRC(frame) {
+			continue
+		}
+
+		unitID := frame[0]
+		response, delay := s.respond(unitID, frame[1:len(frame)-2])
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		if response == nil || unitID == modbus.BroadcastID {
+			continue
+		}
+
+		_, err = conn.Write(modbus.AppendCRC(append([]byte{unitID}, response...)))
+		if err != nil {
+			return
+		}
+	}
+}
+
+// rtuRequestGap is the silence ending an RTU request whose length cannot
+// be told from its function code
+const rtuRequestGap = 20 * time.Millisecond
+
+// readRTURequest reads an RTU request frame, CRC included. Its length
+// follows from the request length of the function registry, or the frame
+// ends with a silence for function codes without one.
+func readRTURequest(conn net.Conn) ([]byte, error) {
+	frame := make([]byte, 2, 256)
+	_, err := io.ReadFull(conn, frame)
+	if err != nil {
+		return nil, err
+	}
+
+	info, ok := modbus.LookupFunction(frame[1])
+	if !ok || info.RequestLength == nil || frame[1]&0x80 != 0 {
+		return readUntilSilence(conn, frame)
+	}
+
+	// Read until the length is known, then the rest of the PDU and the CRC
+	for {
+		length, ok := info.RequestLength(frame[1:])
+		if ok {
+			rest := max(1+length+2-len(frame), 0)
+			frame = append(frame, make([]byte, rest)...)
+			_, err = io.ReadFull(conn, frame[len(frame)-rest:])
+			return frame, err
+		}
+		if len(frame) == cap(frame) {
+			return frame, nil
+		}
+		frame = frame[:len(frame)+1]
+		_, err = io.ReadFull(conn, frame[len(frame)-1:])
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readUntilSilence appends the bytes received to frame until a silence
+func readUntilSilence(conn net.Conn, frame []byte) ([]byte, error) {
+	defer conn.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, 256)
+	for len(frame) < 256 {
+		conn.SetReadDeadline(time.Now().Add(rtuRequestGap))
+		n, err := conn.Read(buf[:256-len(frame)])
+		frame = append(frame, buf[:n]...)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return frame, nil
+}
+
+// respond returns the response PDU to a request PDU and the delay before
+// sending it, from the loaded scenario or the data tables
+func (s *Server) respond(unitID byte, pdu []byte) ([]byte, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scenario != nil {
+		return s.playScenario(unitID, pdu)
+	}
+	return s.handle(unitID, pdu[0], pdu[1:]), 0
+}
+
+// handle executes a request and returns the response PDU,
+// the caller must hold s.mu
+func (s *Server) handle(unitID byte, functionCode byte, data []byte) []byte {
+	// Requests are checked as a real server does, a read of too many
+	// registers would not fit the byte count of the response
+	err := modbus.ValidateRequest(&modbus.PDU{FunctionCode: functionCode, Data: data})
+	if err != nil {
+		return exception(functionCode, exceptionCode(err))
+	}
+
+	u := s.unit(unitID)
+
+	// ValidateRequest checked the length of the data for every case
+	switch functionCode {
+	case modbus.FuncCodeReadCoils, modbus.FuncCodeReadDiscreteInputs:
+		table := u.coils
+		if functionCode == modbus.FuncCodeReadDiscreteInputs {
+			table = u.discreteInputs
+		}
+		address := binary.BigEndian.Uint16(data[0:2])
+		quantity := binary.BigEndian.Uint16(data[2:4])
+
+		values := make([]byte, (quantity+7)/8)
+		for i := uint16(0); i < quantity; i++ {
+			if table[address+i] {
+				values[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append([]byte{functionCode, byte(len(values))}, values...)
+
+	case modbus.FuncCodeReadHoldingRegisters, modbus.FuncCodeReadInputRegisters:
+		table := u.holdingRegisters
+		if functionCode == modbus.FuncCodeReadInputRegisters {
+			table = u.inputRegisters
+		}
+		address := binary.BigEndian.Uint16(data[0:2])
+		quantity := binary.BigEndian.Uint16(data[2:4])
+
+		values := make([]byte, quantity*2)
+		for i := uint16(0); i < quantity; i++ {
+			binary.BigEndian.PutUint16(values[i*2:], table[address+i])
+		}
+		return append([]byte{functionCode, byte(len(values))}, values...)
+
+	case modbus.FuncCodeWriteSingleCoil:
+		address := binary.BigEndian.Uint16(data[0:2])
+		value := binary.BigEndian.Uint16(data[2:4])
+		u.coils[address] = value == 0xFF00
+		s.record(unitID, functionCode, address, value&1)
+		return append([]byte{functionCode}, data...)
+
+	case modbus.FuncCodeWriteSingleRegister:
+		address := binary.BigEndian.Uint16(data[0:2])
+		value := binary.BigEndian.Uint16(data[2:4])
+		u.holdingRegisters[address] = value
+		s.record(unitID, functionCode, address, value)
+		return append([]byte{functionCode}, data...)
+
+	case modbus.FuncCodeWriteMultipleCoils:
+		address := binary.BigEndian.Uint16(data[0:2])
+		quantity := binary.BigEndian.Uint16(data[2:4])
+		for i := uint16(0); i < quantity; i++ {
+			value := data[5+i/8]&(1<<(i%8)) != 0
+			u.coils[address+i] = value
+			var v uint16
+			if value {
+				v = 1
+			}
+			s.record(unitID, functionCode, address+i, v)
+		}
+		return append([]byte{functionCode}, data[0:4]...)
+
+	case modbus.FuncCodeWriteMultipleRegisters:
+		address := binary.BigEndian.Uint16(data[0:2])
+		quantity := binary.BigEndian.Uint16(data[2:4])
+		for i := uint16(0); i < quantity; i++ {
+			value := binary.BigEndian.Uint16(data[5+i*2:])
+			u.holdingRegisters[address+i] = value
+			s.record(unitID, functionCode, address+i, value)
+		}
+		return append([]byte{functionCode}, data[0:4]...)
+
+	case modbus.FuncCodeMaskWriteRegister:
+		address := binary.BigEndian.Uint16(data[0:2])
+		andMask := binary.BigEndian.Uint16(data[2:4])
+		orMask := binary.BigEndian.Uint16(data[4:6])
+		value := u.holdingRegisters[address]&andMask | orMask&^andMask
+		u.holdingRegisters[address] = value
+		s.record(unitID, functionCode, address, value)
+		return append([]byte{functionCode}, data...)
+	}
+
+	return exception(functionCode, modbus.ExceptionIllegalFunction)
+}
+
+// record appends a write, the caller must hold s.mu
+func (s *Server) record(unitID byte, functionCode byte, address uint16, value uint16) {
+	s.writes = append(s.writes, Write{
+		UnitID:       unitID,
+		FunctionCode: functionCode,
+		Address:      address,
+		Value:        value,
+	})
+}
+
+func exception(functionCode byte, exceptionCode byte) []byte {
+	return []byte{functionCode | 0x80, exceptionCode}
+}
+
+// exceptionCode maps a validation error to the exception sent back
+func exceptionCode(err error) byte {
+	var modbusErr *modbus.ModbusError
+	switch {
+	case errors.As(err, &modbusErr):
+		return modbusErr.ExceptionCode
+	case errors.Is(err, modbus.ErrInvalidAddress):
+		return modbus.ExceptionIllegalDataAddress
+	}
+	return modbus.ExceptionIllegalDataValue
+}
@@ -0,0 +1,103 @@
+package modbustest
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SamyFrancelet/modbus"
+)
+
+// Requests that ValidateRequest rejects are answered with an exception
+func TestServerRejectsInvalidRequests(t *testing.T) {
+	s := NewServer(t)
+	c := modbus.NewTCPClient(s.Addr())
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tests := []struct {
+		pdu  *modbus.PDU
+		want error
+	}{
+		// 200 registers overflow the byte count of the response
+		{&modbus.PDU{FunctionCode: modbus.FuncCodeReadHoldingRegisters, Data: []byte{0, 0, 0, 200}}, modbus.ErrIllegalDataValue},
+		{&modbus.PDU{FunctionCode: modbus.FuncCodeReadCoils, Data: []byte{0xFF, 0xFF, 0, 2}}, modbus.ErrIllegalDataAddress},
+		{&modbus.PDU{FunctionCode: modbus.FuncCodeWriteMultipleRegisters, Data: []byte{0, 0, 0, 2, 2, 0, 1}}, modbus.ErrIllegalDataValue},
+		{&modbus.PDU{FunctionCode: 0x41, Data: []byte{1}}, modbus.ErrIllegalFunction},
+	}
+	for _, tt := range tests {
+		_, err := c.SendRawPDU(1, tt.pdu)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("function 0x%02X % X: got %v, want %v", tt.pdu.FunctionCode, tt.pdu.Data, err, tt.want)
+		}
+	}
+	s.AssertNoWrites()
+}
+
+func TestRTUServer(t *testing.T) {
+	s := NewRTUServer(t)
+	s.SetHoldingRegister(1, 10, 0x1234)
+
+	c := modbus.NewRTUOverTCPClient(s.Addr())
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	values, err := c.ReadHoldingRegisters(1, 10, 2)
+	if err != nil || len(values) != 2 || values[0] != 0x1234 {
+		t.Fatalf("ReadHoldingRegisters() = %v, %v, want [4660 0]", values, err)
+	}
+
+	if err := c.WriteMultipleRegisters(1, 20, []uint16{7, 8}); err != nil {
+		t.Fatal(err)
+	}
+	s.AssertReceivedWrite(1, 21, 8)
+
+	// A function code the server does not know ends with a silence
+	_, err = c.SendRawPDU(1, &modbus.PDU{FunctionCode: 0x41, Data: []byte{1, 2}})
+	if !errors.Is(err, modbus.ErrIllegalFunction) {
+		t.Errorf("SendRawPDU() = %v, want %v", err, modbus.ErrIllegalFunction)
+	}
+}
+
+// Registered vendor function codes are framed from the registry
+func TestRTUServerRegisteredFunction(t *testing.T) {
+	byteCount := func(pdu []byte) (int, bool) {
+		if len(pdu) < 2 {
+			return 0, false
+		}
+		return 2 + int(pdu[1]), true
+	}
+	err := modbus.RegisterFunction(modbus.FunctionInfo{
+		Code:           0x42,
+		Name:           "vendor echo",
+		RequestLength:  byteCount,
+		ResponseLength: byteCount,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewRTUServer(t)
+	scenario, err := ParseScenario(strings.NewReader("1 42 02 AABB => 42 01 CC\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.LoadScenario(scenario)
+
+	c := modbus.NewRTUOverTCPClient(s.Addr())
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	response, err := c.SendRawPDU(1, &modbus.PDU{FunctionCode: 0x42, Data: []byte{2, 0xAA, 0xBB}})
+	if err != nil || !bytes.Equal(response.Data, []byte{1, 0xCC}) {
+		t.Fatalf("SendRawPDU() = %v, %v, want 01 CC", response, err)
+	}
+	s.AssertScenarioDone()
+}
@@ -0,0 +1,58 @@
+package modbus
+
+import (
+	"context"
+	"sync"
+)
+
+// Device identifies a slave reachable through a client
+type Device struct {
+	Client  Client
+	SlaveID byte
+}
+
+// WriteResult is the outcome of a write to one device
+type WriteResult struct {
+	Device Device
+	Err    error
+}
+
+// WriteToAll writes value to the holding register at address of every
+// device and returns one result per device, in the order of devices.
+// Devices sharing a client are written one after the other, as they share
+// a connection or serial bus, while distinct clients are written
+// concurrently. To reach every device of an RTU bus with a single frame,
+// pass a Device with SlaveID BroadcastID.
+// Writes not yet issued when ctx is done fail with ctx.Err(), and ctx
+// also bounds the writes in flight of clients implementing ContextClient.
+func WriteToAll(ctx context.Context, devices []Device, address uint16, value uint16) []WriteResult {
+	results := make([]WriteResult, len(devices))
+
+	groups := make(map[Client][]int)
+	var order []Client
+	for i, device := range devices {
+		results[i].Device = device
+		if _, ok := groups[device.Client]; !ok {
+			order = append(order, device.Client)
+		}
+		groups[device.Client] = append(groups[device.Client], i)
+	}
+
+	var wg sync.WaitGroup
+	for _, client := range order {
+		wg.Add(1)
+		go func(client Client, indexes []int) {
+			defer wg.Done()
+			for _, i := range indexes {
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					continue
+				}
+				results[i].Err = writeSingleRegister(ctx, client, devices[i].SlaveID, address, value)
+			}
+		}(client, groups[client])
+	}
+	wg.Wait()
+
+	return results
+}
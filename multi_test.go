@@ -0,0 +1,64 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// connectSilentServer returns a client connected to a server accepting
+// the connection but never answering
+func connectSilentServer(t *testing.T) *TCPClient {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			<-done
+		}
+	}()
+
+	c := NewTCPClient(listener.Addr().String())
+	c.SetTimeout(5 * time.Second)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// assertCanceledInFlight checks that run gives up on its request in
+// flight when its context expires
+func assertCanceledInFlight(t *testing.T, run func(ctx context.Context) error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := run(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %v", elapsed)
+	}
+}
+
+// A write in flight is abandoned when ctx is done
+func TestWriteToAllCancelsWritesInFlight(t *testing.T) {
+	c := connectSilentServer(t)
+	assertCanceledInFlight(t, func(ctx context.Context) error {
+		return WriteToAll(ctx, []Device{{Client: c, SlaveID: 1}}, 0, 1)[0].Err
+	})
+}
@@ -0,0 +1,92 @@
+package modbus
+
+import (
+	"fmt"
+	"sync"
+)
+
+// NameResolver gives a human-friendly name to the device at unitID behind
+// endpoint, the address of a TCP client or the serial device of an RTU
+// or ASCII client
+type NameResolver interface {
+	Name(endpoint string, unitID byte) string
+}
+
+// DeviceNames is a NameResolver backed by registered names. Devices
+// without a name are named after their endpoint and unit ID.
+type DeviceNames struct {
+	mu        sync.RWMutex
+	endpoints map[string]string
+	units     map[deviceKey]string
+}
+
+type deviceKey struct {
+	endpoint string
+	unitID   byte
+}
+
+// NewDeviceNames creates an empty name registry
+func NewDeviceNames() *DeviceNames {
+	return &DeviceNames{
+		endpoints: make(map[string]string),
+		units:     make(map[deviceKey]string),
+	}
+}
+
+// RegisterEndpoint names an endpoint, its devices are named
+// "<name> unit <unitID>"
+func (n *DeviceNames) RegisterEndpoint(endpoint string, name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.endpoints[endpoint] = name
+}
+
+// Register names the device at unitID behind endpoint
+func (n *DeviceNames) Register(endpoint string, unitID byte, name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.units[deviceKey{endpoint, unitID}] = name
+}
+
+// Name returns the name of a device
+func (n *DeviceNames) Name(endpoint string, unitID byte) string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if name, ok := n.units[deviceKey{endpoint, unitID}]; ok {
+		return name
+	}
+	if name, ok := n.endpoints[endpoint]; ok {
+		endpoint = name
+	}
+	return fmt.Sprintf("%s unit %d", endpoint, unitID)
+}
+
+// DeviceError is a request error annotated with the name of the device
+type DeviceError struct {
+	Device   string
+	Endpoint string
+	UnitID   byte
+	Err      error
+}
+
+func (e *DeviceError) Error() string {
+	return e.Device + ": " + e.Err.Error()
+}
+
+func (e *DeviceError) Unwrap() error {
+	return e.Err
+}
+
+// nameError wraps err in a DeviceError if names resolves devices
+func nameError(names NameResolver, endpoint string, unitID byte, err error) error {
+	if err == nil || names == nil {
+		return err
+	}
+	return &DeviceError{
+		Device:   names.Name(endpoint, unitID),
+		Endpoint: endpoint,
+		UnitID:   unitID,
+		Err:      err,
+	}
+}
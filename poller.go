@@ -0,0 +1,230 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PollItem is a range of a table read periodically
+type PollItem struct {
+	// Name identifies the item in results
+	Name     string
+	SlaveID  byte
+	Table    Table
+	Address  uint16
+	Quantity uint16
+	Interval time.Duration
+}
+
+// PollResult is the outcome of one read of a poll item
+type PollResult struct {
+	Item PollItem
+	Time time.Time
+	// Bits holds the values of coils and discrete inputs, Registers those
+	// of registers
+	Bits      []bool
+	Registers []uint16
+	// Changed is set when the values differ from the last successful read,
+	// and on the first one
+	Changed bool
+	Err     error
+}
+
+// Poller reads poll items every interval, one request at a time, and
+// delivers the results to a callback and a channel
+type Poller struct {
+	client ContextClient
+
+	mu          sync.Mutex
+	items       []*pollState
+	onResult    func(PollResult)
+	results     chan PollResult
+	onlyChanges bool
+	retry       *RetryPolicy
+	dropped     atomic.Uint64
+}
+
+// pollState is a poll item with its schedule and last values
+type pollState struct {
+	item      PollItem
+	next      time.Time
+	ok        bool
+	bits      []bool
+	registers []uint16
+}
+
+// NewPoller creates a poller reading through client
+func NewPoller(client ContextClient) *Poller {
+	return &Poller{client: client}
+}
+
+// Add adds an item, polled from the next cycle
+func (p *Poller) Add(item PollItem) error {
+	limit := maxReadRegisters
+	if item.Table.isBits() {
+		limit = maxReadBits
+	}
+	if item.Quantity == 0 || int(item.Quantity) > limit {
+		return fmt.Errorf("%w: poll item %q", ErrInvalidQuantity, item.Name)
+	}
+	if item.Interval <= 0 {
+		return fmt.Errorf("poll item %q: interval must be positive", item.Name)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.items = append(p.items, &pollState{item: item, next: time.Now()})
+	return nil
+}
+
+// OnResult sets a function called with every result, nil removes it. It
+// runs on the polling goroutine and delays the next reads.
+func (p *Poller) OnResult(fn func(PollResult)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onResult = fn
+}
+
+// Results returns a channel delivering the results, buffering up to size
+// of them. When the reader falls behind the oldest results are dropped.
+// The channel is created by the first call.
+func (p *Poller) Results(size int) <-chan PollResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.results == nil {
+		p.results = make(chan PollResult, max(size, 1))
+	}
+	return p.results
+}
+
+// Dropped returns the number of results dropped because the channel was full
+func (p *Poller) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// SetOnlyChanges delivers only the results whose values changed, and
+// errors
+func (p *Poller) SetOnlyChanges(onlyChanges bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onlyChanges = onlyChanges
+}
+
+// SetRetryPolicy sets how failed reads are retried within a cycle, the
+// policy of the client if nil
+func (p *Poller) SetRetryPolicy(policy *RetryPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retry = policy
+}
+
+// Run polls until ctx is done and returns ctx.Err()
+func (p *Poller) Run(ctx context.Context) error {
+	for {
+		// Without items, check again for added ones after a while
+		wait := time.Second
+		p.mu.Lock()
+		for i, state := range p.items {
+			if i == 0 || time.Until(state.next) < wait {
+				wait = time.Until(state.next)
+			}
+		}
+		p.mu.Unlock()
+
+		if err := sleepContext(ctx, max(wait, 0)); err != nil {
+			return err
+		}
+
+		p.mu.Lock()
+		now := time.Now()
+		var due []*pollState
+		for _, state := range p.items {
+			if !state.next.After(now) {
+				due = append(due, state)
+			}
+		}
+		p.mu.Unlock()
+
+		for _, state := range due {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			p.poll(ctx, state)
+		}
+	}
+}
+
+// poll reads an item and delivers its result
+func (p *Poller) poll(ctx context.Context, state *pollState) {
+	p.mu.Lock()
+	if p.retry != nil {
+		ctx = WithRetryPolicy(ctx, p.retry)
+	}
+	p.mu.Unlock()
+
+	item := state.item
+	result := PollResult{Item: item}
+	switch item.Table {
+	case TableCoils:
+		result.Bits, result.Err = p.client.ReadCoilsContext(ctx, item.SlaveID, item.Address, item.Quantity)
+	case TableDiscreteInputs:
+		result.Bits, result.Err = p.client.ReadDiscreteInputsContext(ctx, item.SlaveID, item.Address, item.Quantity)
+	case TableHoldingRegisters:
+		result.Registers, result.Err = p.client.ReadHoldingRegistersContext(ctx, item.SlaveID, item.Address, item.Quantity)
+	case TableInputRegisters:
+		result.Registers, result.Err = p.client.ReadInputRegistersContext(ctx, item.SlaveID, item.Address, item.Quantity)
+	}
+	result.Time = time.Now()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	p.mu.Lock()
+	// Cycles missed while the bus was busy are skipped
+	state.next = state.next.Add(item.Interval)
+	if state.next.Before(result.Time) {
+		state.next = result.Time.Add(item.Interval)
+	}
+	if result.Err == nil {
+		result.Changed = !state.ok || !slices.Equal(state.bits, result.Bits) ||
+			!slices.Equal(state.registers, result.Registers)
+		state.ok, state.bits, state.registers = true, slices.Clone(result.Bits), slices.Clone(result.Registers)
+	}
+	fn := p.onResult
+	results := p.results
+	onlyChanges := p.onlyChanges
+	p.mu.Unlock()
+
+	if onlyChanges && result.Err == nil && !result.Changed {
+		return
+	}
+	if fn != nil {
+		fn(result)
+	}
+	if results != nil {
+		p.deliver(results, result)
+	}
+}
+
+// deliver queues a result, dropping the oldest one if needed
+func (p *Poller) deliver(results chan PollResult, result PollResult) {
+	for {
+		select {
+		case results <- result:
+			return
+		default:
+		}
+
+		select {
+		case <-results:
+			p.dropped.Add(1)
+		default:
+		}
+	}
+}
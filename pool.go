@@ -0,0 +1,259 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TCPPool implements Modbus TCP client over several connections to the
+// same server. Each request goes out the connection with the fewest
+// requests in flight, so goroutines polling in parallel do not serialize
+// on one socket. Every connection multiplexes transaction IDs within its
+// matching window.
+type TCPPool struct {
+	*GenericClient
+
+	address string
+	dialMu  sync.Mutex
+
+	mu         sync.Mutex
+	conns      []*poolConn
+	checkEvery time.Duration
+	check      func(ctx context.Context, conn *TCPClient) error
+	checkStop  chan struct{}
+	onError    func(error)
+}
+
+// poolConn is a connection of a pool with its usage
+type poolConn struct {
+	client   *TCPClient
+	inFlight int
+	lastUsed time.Time
+}
+
+// NewTCPPool creates a pool of size connections to address, at least one
+func NewTCPPool(address string, size int) *TCPPool {
+	p := &TCPPool{address: address}
+	for range max(size, 1) {
+		p.conns = append(p.conns, &poolConn{client: NewTCPClient(address)})
+	}
+	p.GenericClient = NewGenericClient(p)
+	return p
+}
+
+// Size returns the number of connections of the pool
+func (p *TCPPool) Size() int {
+	return len(p.conns)
+}
+
+// Connect opens every connection. It only fails if none can be opened;
+// the others are retried by the health check.
+func (p *TCPPool) Connect() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	for _, conn := range p.conns {
+		err := conn.client.Connect()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(p.conns) {
+		return fmt.Errorf("failed to open any connection to %s: %w", p.address, errors.Join(errs...))
+	}
+
+	if p.checkEvery > 0 && p.checkStop == nil {
+		p.checkStop = make(chan struct{})
+		go p.healthCheck(p.checkEvery, p.checkStop)
+	}
+	return nil
+}
+
+// Close closes every connection
+func (p *TCPPool) Close() error {
+	p.dialMu.Lock()
+	defer p.dialMu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.checkStop != nil {
+		close(p.checkStop)
+		p.checkStop = nil
+	}
+
+	var errs []error
+	for _, conn := range p.conns {
+		errs = append(errs, conn.client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// SetHealthCheck checks the connections every interval: dropped
+// connections are redialed and connections idle for the interval are
+// tested with check, if not nil, and redialed when it fails. Zero
+// disables it. It takes effect on the next Connect.
+func (p *TCPPool) SetHealthCheck(interval time.Duration, check func(ctx context.Context, conn *TCPClient) error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.checkEvery = interval
+	p.check = check
+}
+
+// OnError sets a function called with the errors of the health check,
+// nil discards them
+func (p *TCPPool) OnError(fn func(error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onError = fn
+}
+
+// reportError passes err to the OnError function, the caller must not
+// hold p.mu
+func (p *TCPPool) reportError(err error) {
+	p.mu.Lock()
+	fn := p.onError
+	p.mu.Unlock()
+
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// healthCheck checks the connections every interval until stop is closed
+func (p *TCPPool) healthCheck(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		check := p.check
+		var idle []*poolConn
+		for _, conn := range p.conns {
+			if conn.inFlight == 0 && time.Since(conn.lastUsed) >= interval {
+				// Counted in flight so requests prefer other connections
+				conn.inFlight++
+				idle = append(idle, conn)
+			}
+		}
+		p.mu.Unlock()
+
+		for _, conn := range idle {
+			err := p.checkConn(conn.client, interval, check, stop)
+			if err != nil {
+				p.reportError(err)
+			}
+			p.release(conn)
+		}
+	}
+}
+
+// checkConn redials conn if it is dropped or fails check, unless stop
+// is closed
+func (p *TCPPool) checkConn(conn *TCPClient, timeout time.Duration, check func(ctx context.Context, conn *TCPClient) error, stop <-chan struct{}) error {
+	if conn.Supervisor().State() == StateConnected {
+		if check == nil {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := check(ctx, conn)
+		cancel()
+
+		// An exception response proves the connection works
+		var modbusErr *ModbusError
+		if err == nil || errors.As(err, &modbusErr) {
+			return nil
+		}
+		conn.Close()
+	}
+
+	// Close waits for the dial rather than leaving a connection open
+	p.dialMu.Lock()
+	defer p.dialMu.Unlock()
+	select {
+	case <-stop:
+		return nil
+	default:
+	}
+	return conn.Connect()
+}
+
+// acquire returns the connected connection with the fewest requests in
+// flight, or any connection if none is connected
+func (p *TCPPool) acquire() *poolConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *poolConn
+	bestConnected := false
+	for _, conn := range p.conns {
+		connected := conn.client.Supervisor().State() == StateConnected
+		if best == nil || (connected && !bestConnected) ||
+			(connected == bestConnected && conn.inFlight < best.inFlight) {
+			best = conn
+			bestConnected = connected
+		}
+	}
+
+	best.inFlight++
+	return best
+}
+
+// release returns a connection taken by acquire
+func (p *TCPPool) release(conn *poolConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conn.inFlight--
+	conn.lastUsed = time.Now()
+}
+
+// Endpoint returns the address of the server
+func (p *TCPPool) Endpoint() string {
+	return p.address
+}
+
+// Send sends a request once on the connection with the fewest requests in
+// flight, implementing Transporter
+func (p *TCPPool) Send(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	conn := p.acquire()
+	defer p.release(conn)
+	return conn.client.Send(ctx, slaveID, pdu)
+}
+
+// each applies a setting to every connection
+func (p *TCPPool) each(set func(conn *TCPClient)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, conn := range p.conns {
+		set(conn.client)
+	}
+}
+
+// SetTimeout sets the communication timeout on every connection
+func (p *TCPPool) SetTimeout(timeout time.Duration) {
+	p.each(func(conn *TCPClient) { conn.SetTimeout(timeout) })
+}
+
+// SetMatchingWindow sets the requests in flight on every connection
+func (p *TCPPool) SetMatchingWindow(n int) {
+	p.each(func(conn *TCPClient) { conn.SetMatchingWindow(n) })
+}
+
+// SetAutoReconnect makes requests failing on a dropped connection redial
+// it and retry, on every connection
+func (p *TCPPool) SetAutoReconnect(retries int, backoff Backoff) {
+	p.each(func(conn *TCPClient) { conn.SetAutoReconnect(retries, backoff) })
+}
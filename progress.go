@@ -0,0 +1,158 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+)
+
+// Progress reports how far an operation spanning several frames went
+type Progress struct {
+	Name string
+	// Done is the number of frames completed
+	Done int
+	// Total is the number of frames expected, 0 when unknown such as while
+	// following device identification continuations
+	Total int
+}
+
+// Percent returns the completion in percent, 0 while the total is unknown
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return 100 * float64(p.Done) / float64(p.Total)
+}
+
+// ProgressFunc receives the progress of an operation after every frame
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context reporting the progress of the
+// operations spanning several frames made with it to fn: transactions,
+// range reads and writes and device identification reads
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// reportProgress reports progress to the function of ctx, if any
+func reportProgress(ctx context.Context, progress Progress) {
+	if fn, _ := ctx.Value(progressKey{}).(ProgressFunc); fn != nil {
+		fn(progress)
+	}
+}
+
+// Transaction is a logical operation made of several frames, sent one
+// after the other. It stops at the first frame failing or when its
+// context is done.
+//
+//	err := NewTransaction("upload recipe").
+//		Frame(func(ctx context.Context) error { ... }).
+//		Frame(func(ctx context.Context) error { ... }).
+//		OnProgress(func(p Progress) { ... }).
+//		Run(ctx)
+type Transaction struct {
+	name       string
+	frames     []func(ctx context.Context) error
+	onProgress ProgressFunc
+}
+
+// NewTransaction creates an empty transaction named name in progress
+// reports and errors
+func NewTransaction(name string) *Transaction {
+	return &Transaction{name: name}
+}
+
+// Frame adds a frame, a function sending one request
+func (t *Transaction) Frame(fn func(ctx context.Context) error) *Transaction {
+	t.frames = append(t.frames, fn)
+	return t
+}
+
+// OnProgress sets a function receiving the progress after every frame,
+// in addition to the one of the context given to Run
+func (t *Transaction) OnProgress(fn ProgressFunc) *Transaction {
+	t.onProgress = fn
+	return t
+}
+
+// Len returns the number of frames
+func (t *Transaction) Len() int {
+	return len(t.frames)
+}
+
+// Run sends the frames in order. Frames not yet sent when ctx is done are
+// abandoned and ctx.Err() is returned.
+func (t *Transaction) Run(ctx context.Context) error {
+	for i, frame := range t.frames {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := frame(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("%s: frame %d of %d: %w", t.name, i+1, len(t.frames), err)
+		}
+
+		progress := Progress{Name: t.name, Done: i + 1, Total: len(t.frames)}
+		if t.onProgress != nil {
+			t.onProgress(progress)
+		}
+		reportProgress(ctx, progress)
+	}
+	return nil
+}
+
+// ReadRegisterRange reads quantity holding registers starting at address
+// in as many requests as needed, reporting the progress to the function
+// of ctx
+func ReadRegisterRange(ctx context.Context, client ContextClient, slaveID byte, address uint16, quantity int) ([]uint16, error) {
+	err := ValidateRange(address, quantity, 0x10000)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]uint16, 0, quantity)
+	t := NewTransaction("read registers")
+	for offset, chunk := range chunks(quantity, maxReadRegisters) {
+		start := address + uint16(offset)
+		t.Frame(func(ctx context.Context) error {
+			chunkValues, err := client.ReadHoldingRegistersContext(ctx, slaveID, start, uint16(chunk))
+			if err != nil {
+				return err
+			}
+			if len(chunkValues) < chunk {
+				return ErrInvalidResponse
+			}
+			values = append(values, chunkValues[:chunk]...)
+			return nil
+		})
+	}
+
+	err = t.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// WriteRegisterRange writes values to the holding registers starting at
+// address in as many requests as needed, reporting the progress to the
+// function of ctx. Registers written before a failure keep their values.
+func WriteRegisterRange(ctx context.Context, client ContextClient, slaveID byte, address uint16, values []uint16) error {
+	err := ValidateRange(address, len(values), 0x10000)
+	if err != nil {
+		return err
+	}
+
+	t := NewTransaction("write registers")
+	for offset, n := range chunks(len(values), maxWriteRegisters) {
+		chunk := values[offset : offset+n]
+		start := address + uint16(offset)
+		t.Frame(func(ctx context.Context) error {
+			return client.WriteMultipleRegistersContext(ctx, slaveID, start, chunk)
+		})
+	}
+	return t.Run(ctx)
+}
@@ -7,16 +7,26 @@ import (
 
 // Function codes
 const (
-	FuncCodeReadCoils              = 0x01
-	FuncCodeReadDiscreteInputs     = 0x02
-	FuncCodeReadHoldingRegisters   = 0x03
-	FuncCodeReadInputRegisters     = 0x04
-	FuncCodeWriteSingleCoil        = 0x05
-	FuncCodeWriteSingleRegister    = 0x06
-	FuncCodeWriteMultipleCoils     = 0x0F
-	FuncCodeWriteMultipleRegisters = 0x10
+	FuncCodeReadCoils                  = 0x01
+	FuncCodeReadDiscreteInputs         = 0x02
+	FuncCodeReadHoldingRegisters       = 0x03
+	FuncCodeReadInputRegisters         = 0x04
+	FuncCodeWriteSingleCoil            = 0x05
+	FuncCodeWriteSingleRegister        = 0x06
+	FuncCodeDiagnostics                = 0x08
+	FuncCodeGetCommEventCounter        = 0x0B
+	FuncCodeWriteMultipleCoils         = 0x0F
+	FuncCodeWriteMultipleRegisters     = 0x10
+	FuncCodeMaskWriteRegister          = 0x16
+	FuncCodeReadWriteMultipleRegisters = 0x17
+	FuncCodeReadFIFOQueue              = 0x18
+	FuncCodeEncapsulatedInterface      = 0x2B
 )
 
+// BroadcastID is the slave ID addressing every device of a serial bus.
+// Devices execute broadcast writes without responding.
+const BroadcastID = 0
+
 // Exception codes
 const (
 	ExceptionIllegalFunction                    = 0x01
@@ -33,12 +43,130 @@ const (
 var (
 	ErrInvalidResponse = errors.New("invalid response")
 	ErrInvalidCRC      = errors.New("invalid CRC")
+	ErrInvalidLRC      = errors.New("invalid LRC")
 	ErrTimeout         = errors.New("timeout")
 	ErrInvalidSlaveID  = errors.New("invalid slave ID")
 	ErrInvalidAddress  = errors.New("invalid address")
 	ErrInvalidQuantity = errors.New("invalid quantity")
+
+	ErrInvalidProtocolID = errors.New("invalid protocol ID")
+	ErrWriteOnly         = errors.New("device is write-only")
+
+	ErrWordOrderUndetermined = errors.New("word order undetermined")
+	ErrInvalidTimestamp      = errors.New("invalid timestamp")
+	ErrTornRead              = errors.New("values changed during read")
+
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrVerifyFailed       = errors.New("verification failed")
+
+	ErrInvalidTag  = errors.New("invalid tag")
+	ErrUnknownTag  = errors.New("unknown tag")
+	ErrReadOnlyTag = errors.New("tag is read-only")
+
+	ErrSupervisorClosed  = errors.New("supervisor closed")
+	ErrConnectInProgress = errors.New("connection attempt in progress")
+	ErrReconnectBackoff  = errors.New("waiting before reconnecting")
+
+	ErrServerClosed     = errors.New("server closed")
+	ErrConnectionClosed = errors.New("connection closed")
+	ErrNotConnected     = errors.New("not connected")
+	ErrInvalidRequest   = errors.New("invalid request")
+)
+
+// isWriteFunction reports whether the function code writes data
+func isWriteFunction(functionCode byte) bool {
+	switch functionCode {
+	case FuncCodeWriteSingleCoil, FuncCodeWriteSingleRegister,
+		FuncCodeWriteMultipleCoils, FuncCodeWriteMultipleRegisters,
+		FuncCodeMaskWriteRegister:
+		return true
+	}
+	return false
+}
+
+// toProtocolAddress translates a data-model address starting at 1 to the
+// protocol address starting at 0 if oneBased is set
+func toProtocolAddress(oneBased bool, address uint16) (uint16, error) {
+	if !oneBased {
+		return address, nil
+	}
+	if address == 0 {
+		return 0, fmt.Errorf("%w: address 0 of a one-based device", ErrInvalidAddress)
+	}
+	return address - 1, nil
+}
+
+// alternateReadFunction returns the function code reading the other table
+// of the same kind: input registers for holding registers, discrete inputs
+// for coils, and back
+func alternateReadFunction(functionCode byte) (byte, bool) {
+	switch functionCode {
+	case FuncCodeReadCoils:
+		return FuncCodeReadDiscreteInputs, true
+	case FuncCodeReadDiscreteInputs:
+		return FuncCodeReadCoils, true
+	case FuncCodeReadHoldingRegisters:
+		return FuncCodeReadInputRegisters, true
+	case FuncCodeReadInputRegisters:
+		return FuncCodeReadHoldingRegisters, true
+	}
+	return 0, false
+}
+
+// Exceptions as errors. Exception responses received by clients match
+// them with errors.Is whatever their function code, and a DataStore
+// returns them to send the exception.
+var (
+	ErrIllegalFunction        = &ModbusError{ExceptionCode: ExceptionIllegalFunction}
+	ErrIllegalDataAddress     = &ModbusError{ExceptionCode: ExceptionIllegalDataAddress}
+	ErrIllegalDataValue       = &ModbusError{ExceptionCode: ExceptionIllegalDataValue}
+	ErrSlaveDeviceFailure     = &ModbusError{ExceptionCode: ExceptionSlaveDeviceFailure}
+	ErrAcknowledge            = &ModbusError{ExceptionCode: ExceptionAcknowledge}
+	ErrSlaveDeviceBusy        = &ModbusError{ExceptionCode: ExceptionSlaveDeviceBusy}
+	ErrMemoryParityError      = &ModbusError{ExceptionCode: ExceptionMemoryParityError}
+	ErrGatewayPathUnavailable = &ModbusError{ExceptionCode: ExceptionGatewayPathUnavailable}
+	ErrGatewayTargetFailed    = &ModbusError{ExceptionCode: ExceptionGatewayTargetDeviceFailedToRespond}
 )
 
+// ExceptionError returns the error of an exception code, one of the
+// predeclared errors for the codes of the specification
+func ExceptionError(code byte) error {
+	switch code {
+	case ExceptionIllegalFunction:
+		return ErrIllegalFunction
+	case ExceptionIllegalDataAddress:
+		return ErrIllegalDataAddress
+	case ExceptionIllegalDataValue:
+		return ErrIllegalDataValue
+	case ExceptionSlaveDeviceFailure:
+		return ErrSlaveDeviceFailure
+	case ExceptionAcknowledge:
+		return ErrAcknowledge
+	case ExceptionSlaveDeviceBusy:
+		return ErrSlaveDeviceBusy
+	case ExceptionMemoryParityError:
+		return ErrMemoryParityError
+	case ExceptionGatewayPathUnavailable:
+		return ErrGatewayPathUnavailable
+	case ExceptionGatewayTargetDeviceFailedToRespond:
+		return ErrGatewayTargetFailed
+	}
+	return &ModbusError{ExceptionCode: code}
+}
+
+// exceptionNames names the exception codes of the specification
+var exceptionNames = map[byte]string{
+	ExceptionIllegalFunction:                    "illegal function",
+	ExceptionIllegalDataAddress:                 "illegal data address",
+	ExceptionIllegalDataValue:                   "illegal data value",
+	ExceptionSlaveDeviceFailure:                 "slave device failure",
+	ExceptionAcknowledge:                        "acknowledge",
+	ExceptionSlaveDeviceBusy:                    "slave device busy",
+	ExceptionMemoryParityError:                  "memory parity error",
+	ExceptionGatewayPathUnavailable:             "gateway path unavailable",
+	ExceptionGatewayTargetDeviceFailedToRespond: "gateway target device failed to respond",
+}
+
 // ModbusError represents a Modbus exception
 type ModbusError struct {
 	FunctionCode  byte
@@ -46,10 +174,23 @@ type ModbusError struct {
 }
 
 func (e *ModbusError) Error() string {
+	if e.FunctionCode == 0 {
+		if name, ok := exceptionNames[e.ExceptionCode]; ok {
+			return "modbus exception: " + name
+		}
+		return fmt.Sprintf("modbus exception: exception=0x%02X", e.ExceptionCode)
+	}
 	return fmt.Sprintf("modbus exception: function=0x%02X, exception=0x%02X",
 		e.FunctionCode, e.ExceptionCode)
 }
 
+// Is matches an exception without function code, such as
+// ErrIllegalFunction, against any function code
+func (e *ModbusError) Is(target error) bool {
+	t, ok := target.(*ModbusError)
+	return ok && t.FunctionCode == 0 && t.ExceptionCode == e.ExceptionCode
+}
+
 // PDU represents a Protocol Data Unit
 type PDU struct {
 	FunctionCode byte
@@ -0,0 +1,160 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RecipeStep writes one holding register as part of a recipe
+type RecipeStep struct {
+	Address uint16
+	Value   uint16
+	// Delay is waited after the write, before the next step
+	Delay time.Duration
+}
+
+// RecipeCondition is a holding register value required before a recipe
+// runs. Only the bits set in Mask are compared, all bits if Mask is zero.
+type RecipeCondition struct {
+	Address uint16
+	Value   uint16
+	Mask    uint16
+}
+
+// Recipe is a named set of register values downloaded to a device in order
+type Recipe struct {
+	Name          string
+	Preconditions []RecipeCondition
+	Steps         []RecipeStep
+	// Verify reads back every written register once all steps are done
+	Verify bool
+}
+
+// RecipeStepResult is the outcome of one recipe step
+type RecipeStepResult struct {
+	Step     RecipeStep
+	Written  bool
+	Verified bool
+	ReadBack uint16 // value read during verification
+	Err      error
+}
+
+// RecipeReport is the result of running a recipe against a device
+type RecipeReport struct {
+	Recipe  string
+	SlaveID byte
+	Start   time.Time
+	End     time.Time
+	Steps   []RecipeStepResult
+	// Err is the error that stopped the recipe, nil if it completed
+	Err error
+}
+
+// Run checks the preconditions, writes every step and verifies the
+// written values if requested. It stops at the first error or when ctx
+// is done, which also bounds the request in flight of clients
+// implementing ContextClient.
+func (r *Recipe) Run(ctx context.Context, client Client, slaveID byte) *RecipeReport {
+	report := &RecipeReport{
+		Recipe:  r.Name,
+		SlaveID: slaveID,
+		Start:   time.Now(),
+		Steps:   make([]RecipeStepResult, len(r.Steps)),
+	}
+	for i, step := range r.Steps {
+		report.Steps[i].Step = step
+	}
+
+	report.Err = r.run(ctx, client, slaveID, report)
+	report.End = time.Now()
+	return report
+}
+
+func (r *Recipe) run(ctx context.Context, client Client, slaveID byte, report *RecipeReport) error {
+	for _, cond := range r.Preconditions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		values, err := readHoldingRegisters(ctx, client, slaveID, cond.Address, 1)
+		if err == nil && len(values) < 1 {
+			err = ErrInvalidResponse
+		}
+		if err != nil {
+			return fmt.Errorf("precondition at address %d: %w", cond.Address, err)
+		}
+
+		mask := cond.Mask
+		if mask == 0 {
+			mask = 0xFFFF
+		}
+		if values[0]&mask != cond.Value&mask {
+			return fmt.Errorf("%w: address %d is %d, expected %d",
+				ErrPreconditionFailed, cond.Address, values[0], cond.Value)
+		}
+	}
+
+	for i := range report.Steps {
+		result := &report.Steps[i]
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result.Err = writeSingleRegister(ctx, client, slaveID, result.Step.Address, result.Step.Value)
+		if result.Err != nil {
+			return fmt.Errorf("step %d: %w", i, result.Err)
+		}
+		result.Written = true
+
+		if err := sleepContext(ctx, result.Step.Delay); err != nil {
+			return err
+		}
+	}
+
+	if !r.Verify {
+		return nil
+	}
+
+	for i := range report.Steps {
+		result := &report.Steps[i]
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		values, err := readHoldingRegisters(ctx, client, slaveID, result.Step.Address, 1)
+		if err == nil && len(values) < 1 {
+			err = ErrInvalidResponse
+		}
+		if err != nil {
+			result.Err = err
+			return fmt.Errorf("verify step %d: %w", i, err)
+		}
+
+		result.ReadBack = values[0]
+		if values[0] != result.Step.Value {
+			result.Err = fmt.Errorf("%w: address %d is %d, expected %d",
+				ErrVerifyFailed, result.Step.Address, values[0], result.Step.Value)
+			return fmt.Errorf("step %d: %w", i, result.Err)
+		}
+		result.Verified = true
+	}
+	return nil
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,17 @@
+package modbus
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRecipeCancelsRequestInFlight(t *testing.T) {
+	c := connectSilentServer(t)
+	recipe := &Recipe{
+		Name:  "setpoints",
+		Steps: []RecipeStep{{Address: 0, Value: 1}},
+	}
+	assertCanceledInFlight(t, func(ctx context.Context) error {
+		return recipe.Run(ctx, c, 1).Err
+	})
+}
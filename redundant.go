@@ -0,0 +1,311 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RedundantRTUConfig holds configuration for a redundant RTU bus made of
+// two serial ports wired to the same field devices
+type RedundantRTUConfig struct {
+	Primary *RTUConfig
+	Standby *RTUConfig
+	// FailoverThreshold is the number of consecutive errors on the active
+	// port before switching to the standby port (default 3)
+	FailoverThreshold int
+}
+
+// PortHealth reports the health of one port of a redundant bus
+type PortHealth struct {
+	Device            string
+	Active            bool
+	Connected         bool
+	ConsecutiveErrors int
+	TotalRequests     uint64
+	TotalErrors       uint64
+	LastError         error
+}
+
+// RedundantRTUClient implements Modbus RTU client over a redundant bus.
+// Requests go out the active port and the client fails over to the
+// standby port after repeated errors. The standby port carries no
+// requests, its health is only known once probed.
+type RedundantRTUClient struct {
+	*GenericClient
+
+	mu            sync.Mutex
+	ports         [2]*RTUClient
+	health        [2]PortHealth
+	active        int
+	threshold     int
+	probeInterval time.Duration
+	probeSlaveID  byte
+	probePDU      *PDU
+	probeStop     chan struct{}
+}
+
+// NewRedundantRTUClient creates a new redundant Modbus RTU client
+func NewRedundantRTUClient(config *RedundantRTUConfig) *RedundantRTUClient {
+	threshold := config.FailoverThreshold
+	if threshold <= 0 {
+		threshold = 3
+	}
+
+	c := &RedundantRTUClient{
+		ports:     [2]*RTUClient{NewRTUClient(config.Primary), NewRTUClient(config.Standby)},
+		threshold: threshold,
+		// The bus has no address of its own, any device will do
+		probeSlaveID: 1,
+		probePDU: &PDU{
+			FunctionCode: FuncCodeReadHoldingRegisters,
+			Data:         []byte{0, 0, 0, 1},
+		},
+	}
+	c.health[0].Device = config.Primary.Device
+	c.health[1].Device = config.Standby.Device
+	c.GenericClient = NewGenericClient(c)
+	return c
+}
+
+// Connect opens both serial ports. It only fails if neither port can be
+// opened; a port that fails to open is retried on failover.
+func (c *RedundantRTUClient) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs [2]error
+	for i, port := range c.ports {
+		errs[i] = port.Connect()
+		c.health[i].Connected = errs[i] == nil
+		c.health[i].LastError = errs[i]
+	}
+
+	if errs[0] != nil && errs[1] != nil {
+		return fmt.Errorf("failed to open both ports: %w", errors.Join(errs[0], errs[1]))
+	}
+
+	if errs[c.active] != nil {
+		c.active = 1 - c.active
+	}
+
+	if c.probeInterval > 0 && c.probeStop == nil {
+		c.probeStop = make(chan struct{})
+		go c.probe(c.probeInterval, c.probeStop)
+	}
+	return nil
+}
+
+// Close closes both serial ports
+func (c *RedundantRTUClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.probeStop != nil {
+		close(c.probeStop)
+		c.probeStop = nil
+	}
+
+	var errs []error
+	for i, port := range c.ports {
+		if c.health[i].Connected {
+			errs = append(errs, port.Close())
+			c.health[i].Connected = false
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetTimeout sets the communication timeout on both ports
+func (c *RedundantRTUClient) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, port := range c.ports {
+		port.SetTimeout(timeout)
+	}
+}
+
+// SetWriteOnly marks a device as reachable over a link that never returns
+// responses, on both ports. Writes to it return as soon as they are sent
+// and reads fail with ErrWriteOnly.
+func (c *RedundantRTUClient) SetWriteOnly(slaveID byte, writeOnly bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, port := range c.ports {
+		port.SetWriteOnly(slaveID, writeOnly)
+	}
+}
+
+// SetCapture sends a copy of every frame sent and received on both ports
+// to capture, nil stops capturing
+func (c *RedundantRTUClient) SetCapture(capture *FrameCapture) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, port := range c.ports {
+		port.SetCapture(capture)
+	}
+}
+
+// SetProbeInterval enables periodic checks of the standby port with
+// CheckStandby, so that its health is known before failing over to it.
+// Zero disables probing. It takes effect on the next Connect.
+func (c *RedundantRTUClient) SetProbeInterval(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probeInterval = interval
+}
+
+// SetProbeRequest sets the request sent by CheckStandby, a read of
+// holding register 0 of device 1 by default. Any response, exceptions
+// included, proves the port works.
+func (c *RedundantRTUClient) SetProbeRequest(slaveID byte, pdu *PDU) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probeSlaveID = slaveID
+	c.probePDU = pdu
+}
+
+// CheckStandby sends the probe request on the standby port, opening it if
+// needed, and records the outcome in its health. Requests wait for the
+// check to complete.
+func (c *RedundantRTUClient) CheckStandby(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	standby := 1 - c.active
+	health := &c.health[standby]
+
+	if !health.Connected {
+		err := c.ports[standby].Connect()
+		if err != nil {
+			health.LastError = err
+			return err
+		}
+		health.Connected = true
+	}
+
+	health.TotalRequests++
+	_, err := c.ports[standby].Send(ctx, c.probeSlaveID, c.probePDU)
+	c.record(standby, err)
+
+	// An exception response proves the port works
+	var modbusErr *ModbusError
+	if errors.As(err, &modbusErr) {
+		return nil
+	}
+	return err
+}
+
+// probe checks the standby port every interval until stop is closed
+func (c *RedundantRTUClient) probe(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// The outcome is reported by Health
+		c.CheckStandby(context.Background())
+	}
+}
+
+// Health returns the health of the primary and standby ports
+func (c *RedundantRTUClient) Health() []PortHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	health := make([]PortHealth, len(c.health))
+	copy(health, c.health[:])
+	for i := range health {
+		health[i].Active = i == c.active
+	}
+	return health
+}
+
+// do runs a request on the active port and tracks its health
+func (c *RedundantRTUClient) do(request func(port *RTUClient) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.health[c.active].TotalRequests++
+
+	err := request(c.ports[c.active])
+	if c.record(c.active, err) && c.health[c.active].ConsecutiveErrors >= c.threshold {
+		c.failover()
+	}
+	return err
+}
+
+// record tracks the outcome of a request on port i in its health,
+// reporting whether it was a failure of the port. The caller must hold
+// c.mu.
+func (c *RedundantRTUClient) record(i int, err error) bool {
+	health := &c.health[i]
+
+	// An exception response proves the port works
+	var modbusErr *ModbusError
+	if err == nil || errors.As(err, &modbusErr) {
+		health.ConsecutiveErrors = 0
+		return false
+	}
+
+	// Invalid arguments and reads from write-only devices never reached
+	// the port, and a request the caller canceled or let expire says
+	// nothing about it
+	if errors.Is(err, ErrInvalidQuantity) || errors.Is(err, ErrInvalidAddress) ||
+		errors.Is(err, ErrWriteOnly) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	health.ConsecutiveErrors++
+	health.TotalErrors++
+	health.LastError = err
+	return true
+}
+
+// Endpoint returns the serial device of the active port
+func (c *RedundantRTUClient) Endpoint() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.health[c.active].Device
+}
+
+// Send sends a request once on the active port, implementing Transporter.
+// Every failed attempt of a retried request counts towards failover.
+func (c *RedundantRTUClient) Send(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	var response []byte
+	err := c.do(func(port *RTUClient) (err error) {
+		response, err = port.Send(ctx, slaveID, pdu)
+		return err
+	})
+	return response, err
+}
+
+// failover switches to the standby port, opening it if needed
+func (c *RedundantRTUClient) failover() {
+	standby := 1 - c.active
+	health := &c.health[standby]
+
+	if !health.Connected {
+		err := c.ports[standby].Connect()
+		if err != nil {
+			health.LastError = err
+			return
+		}
+		health.Connected = true
+	}
+
+	health.ConsecutiveErrors = 0
+	c.active = standby
+}
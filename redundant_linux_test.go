@@ -0,0 +1,60 @@
+//go:build linux
+
+package modbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRedundantRTUClientCheckStandby(t *testing.T) {
+	_, primary := openPTY(t)
+	standbyMaster, standby := openPTY(t)
+
+	c := NewRedundantRTUClient(&RedundantRTUConfig{
+		Primary: &RTUConfig{Device: primary, Baud: 9600, DataBits: 8, ReadTimeout: 200 * time.Millisecond},
+		Standby: &RTUConfig{Device: standby, Baud: 9600, DataBits: 8, ReadTimeout: 200 * time.Millisecond},
+	})
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	capture := NewFrameCapture(4)
+	c.SetCapture(capture)
+
+	answered := make(chan struct{})
+	go func() {
+		defer close(answered)
+		buf := make([]byte, maxRTUFrame)
+		standbyMaster.Read(buf)
+		standbyMaster.Write(ModbusCRC16.Append([]byte{1, FuncCodeReadHoldingRegisters, 2, 0, 7}))
+	}()
+	if err := c.CheckStandby(context.Background()); err != nil {
+		t.Fatalf("CheckStandby() = %v", err)
+	}
+	<-answered
+
+	if frame := <-capture.Frames(); frame.Direction != FrameSent {
+		t.Errorf("captured %v frame, want the probe sent", frame.Direction)
+	}
+
+	// Nobody answers on the standby port anymore
+	if err := c.CheckStandby(context.Background()); err == nil {
+		t.Fatal("CheckStandby() = nil, want an error")
+	}
+	health := c.Health()[1]
+	if health.Active || health.TotalRequests != 2 || health.TotalErrors != 1 || health.LastError == nil {
+		t.Errorf("standby health = %+v", health)
+	}
+	if c.Health()[0].TotalRequests != 0 {
+		t.Error("probe counted on the active port")
+	}
+
+	c.SetWriteOnly(2, true)
+	if _, err := c.ReadHoldingRegisters(2, 0, 1); !errors.Is(err, ErrWriteOnly) {
+		t.Errorf("ReadHoldingRegisters() = %v, want %v", err, ErrWriteOnly)
+	}
+}
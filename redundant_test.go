@@ -0,0 +1,35 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// Only errors from the port count towards failover
+func TestRedundantRTUClientFailoverErrors(t *testing.T) {
+	c := NewRedundantRTUClient(&RedundantRTUConfig{
+		Primary:           &RTUConfig{Device: "/dev/null/primary"},
+		Standby:           &RTUConfig{Device: "/dev/null/standby"},
+		FailoverThreshold: 2,
+	})
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{ErrTimeout, 1},
+		{ErrWriteOnly, 1},
+		{fmt.Errorf("read: %w", context.DeadlineExceeded), 1},
+		{context.Canceled, 1},
+		{ErrInvalidQuantity, 1},
+		{&ModbusError{FunctionCode: 3, ExceptionCode: ExceptionIllegalDataAddress}, 0},
+		{ErrInvalidCRC, 1},
+	}
+	for _, tt := range tests {
+		c.do(func(*RTUClient) error { return tt.err })
+		if got := c.Health()[0].ConsecutiveErrors; got != tt.want {
+			t.Errorf("after %v: %d consecutive errors, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+package modbus
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Table is a data table of a device
+type Table int
+
+const (
+	TableCoils Table = iota
+	TableDiscreteInputs
+	TableHoldingRegisters
+	TableInputRegisters
+)
+
+func (t Table) String() string {
+	switch t {
+	case TableCoils:
+		return "coils"
+	case TableDiscreteInputs:
+		return "discrete inputs"
+	case TableHoldingRegisters:
+		return "holding registers"
+	case TableInputRegisters:
+		return "input registers"
+	}
+	return "unknown"
+}
+
+// isBits reports whether the table holds bits rather than registers
+func (t Table) isBits() bool {
+	return t == TableCoils || t == TableDiscreteInputs
+}
+
+// DataType is the type of the value of a tag
+type DataType int
+
+const (
+	// TypeBool is a coil or discrete input
+	TypeBool DataType = iota
+	TypeUint16
+	TypeInt16
+	TypeUint32
+	TypeInt32
+	TypeFloat32
+	TypeUint64
+	TypeInt64
+	TypeFloat64
+	// TypeString is text of Tag.Length characters, 2 per register
+	TypeString
+)
+
+// Tag is a named value of a device
+type Tag struct {
+	Name    string
+	Table   Table
+	Address uint16
+	Type    DataType
+	// Length is the number of characters of a TypeString tag
+	Length int
+	// Scale and Offset convert numeric values to engineering units:
+	// value = raw * Scale + Offset. A zero Scale means 1.
+	Scale  float64
+	Offset float64
+}
+
+// size returns the number of registers or bits of the tag
+func (t *Tag) size() int {
+	switch t.Type {
+	case TypeUint32, TypeInt32, TypeFloat32:
+		return 2
+	case TypeUint64, TypeInt64, TypeFloat64:
+		return 4
+	case TypeString:
+		return (t.Length + 1) / 2
+	}
+	return 1
+}
+
+// scale returns the scale of the tag, 1 if unset
+func (t *Tag) scale() float64 {
+	if t.Scale == 0 {
+		return 1
+	}
+	return t.Scale
+}
+
+// RegisterMap reads and writes the tags of a device by name. Reads of
+// several tags are coalesced into as few requests as possible. Numeric
+// tags are float64 values in engineering units, TypeBool tags bool and
+// TypeString tags string.
+type RegisterMap struct {
+	client  Client
+	slaveID byte
+	decoder Decoder
+	encoder Encoder
+	maxGap  int
+	tags    []Tag
+	byName  map[string]int
+}
+
+// NewRegisterMap creates an empty register map of the device slaveID
+func NewRegisterMap(client Client, slaveID byte) *RegisterMap {
+	return &RegisterMap{
+		client:  client,
+		slaveID: slaveID,
+		byName:  make(map[string]int),
+	}
+}
+
+// SetOrder sets the word and byte order of the values of the device
+func (m *RegisterMap) SetOrder(wordOrder WordOrder, byteOrder ByteOrder) {
+	m.decoder = Decoder{WordOrder: wordOrder, ByteOrder: byteOrder}
+	m.encoder = Encoder{WordOrder: wordOrder, ByteOrder: byteOrder}
+}
+
+// SetMaxGap lets coalesced reads span up to gap unused registers or bits
+// between tags, trading a larger response for fewer requests
+func (m *RegisterMap) SetMaxGap(gap int) {
+	m.maxGap = max(gap, 0)
+}
+
+// Add declares a tag
+func (m *RegisterMap) Add(tag Tag) error {
+	if _, ok := m.byName[tag.Name]; ok {
+		return fmt.Errorf("%w: duplicate tag %q", ErrInvalidTag, tag.Name)
+	}
+	if tag.Table.isBits() != (tag.Type == TypeBool) {
+		return fmt.Errorf("%w: tag %q of type %d in %s", ErrInvalidTag, tag.Name, tag.Type, tag.Table)
+	}
+	if tag.Type == TypeString && tag.Length <= 0 {
+		return fmt.Errorf("%w: tag %q without length", ErrInvalidTag, tag.Name)
+	}
+	if int(tag.Address)+tag.size() > 0x10000 {
+		return fmt.Errorf("%w: tag %q", ErrInvalidAddress, tag.Name)
+	}
+
+	m.byName[tag.Name] = len(m.tags)
+	m.tags = append(m.tags, tag)
+	return nil
+}
+
+// Tags returns the declared tags
+func (m *RegisterMap) Tags() []Tag {
+	return slices.Clone(m.tags)
+}
+
+// ReadTag reads the value of a tag
+func (m *RegisterMap) ReadTag(name string) (any, error) {
+	i, ok := m.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTag, name)
+	}
+
+	values, err := m.read([]*Tag{&m.tags[i]})
+	if err != nil {
+		return nil, err
+	}
+	return values[name], nil
+}
+
+// ReadAll reads the values of every tag
+func (m *RegisterMap) ReadAll() (map[string]any, error) {
+	tags := make([]*Tag, len(m.tags))
+	for i := range m.tags {
+		tags[i] = &m.tags[i]
+	}
+	return m.read(tags)
+}
+
+// tagBlock is a range of a table read in one request
+type tagBlock struct {
+	table   Table
+	address int
+	end     int
+	tags    []*Tag
+}
+
+// read reads tags in coalesced blocks
+func (m *RegisterMap) read(tags []*Tag) (map[string]any, error) {
+	values := make(map[string]any, len(tags))
+	for _, block := range m.blocks(tags) {
+		err := m.readBlock(block, values)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// blocks groups tags into ranges of the same table within the request
+// limits, allowing gaps of up to maxGap
+func (m *RegisterMap) blocks(tags []*Tag) []*tagBlock {
+	sorted := slices.Clone(tags)
+	slices.SortFunc(sorted, func(a, b *Tag) int {
+		if a.Table != b.Table {
+			return int(a.Table) - int(b.Table)
+		}
+		return int(a.Address) - int(b.Address)
+	})
+
+	var blocks []*tagBlock
+	var current *tagBlock
+	for _, tag := range sorted {
+		start, end := int(tag.Address), int(tag.Address)+tag.size()
+		limit := maxReadRegisters
+		if tag.Table.isBits() {
+			limit = maxReadBits
+		}
+
+		if current != nil && current.table == tag.Table &&
+			start <= current.end+m.maxGap && max(end, current.end)-current.address <= limit {
+			current.end = max(current.end, end)
+			current.tags = append(current.tags, tag)
+			continue
+		}
+
+		current = &tagBlock{table: tag.Table, address: start, end: end, tags: []*Tag{tag}}
+		blocks = append(blocks, current)
+	}
+	return blocks
+}
+
+// readBlock reads a block and decodes the values of its tags
+func (m *RegisterMap) readBlock(block *tagBlock, values map[string]any) error {
+	address, quantity := uint16(block.address), uint16(block.end-block.address)
+
+	var bits []bool
+	var registers []uint16
+	var err error
+	switch block.table {
+	case TableCoils:
+		bits, err = m.client.ReadCoils(m.slaveID, address, quantity)
+	case TableDiscreteInputs:
+		bits, err = m.client.ReadDiscreteInputs(m.slaveID, address, quantity)
+	case TableHoldingRegisters:
+		registers, err = m.client.ReadHoldingRegisters(m.slaveID, address, quantity)
+	case TableInputRegisters:
+		registers, err = m.client.ReadInputRegisters(m.slaveID, address, quantity)
+	}
+	if err != nil {
+		return err
+	}
+	if len(bits)+len(registers) < int(quantity) {
+		return ErrInvalidResponse
+	}
+
+	for _, tag := range block.tags {
+		offset := int(tag.Address) - block.address
+		if block.table.isBits() {
+			values[tag.Name] = bits[offset]
+			continue
+		}
+
+		value, err := m.decode(tag, registers[offset:offset+tag.size()])
+		if err != nil {
+			return fmt.Errorf("tag %q: %w", tag.Name, err)
+		}
+		values[tag.Name] = value
+	}
+	return nil
+}
+
+// decode converts the registers of a tag to its value
+func (m *RegisterMap) decode(tag *Tag, registers []uint16) (any, error) {
+	if tag.Type == TypeString {
+		return m.decoder.String(registers), nil
+	}
+
+	var raw float64
+	var err error
+	switch tag.Type {
+	case TypeUint16:
+		var v uint16
+		v, err = m.decoder.Uint16(registers)
+		raw = float64(v)
+	case TypeInt16:
+		var v int16
+		v, err = m.decoder.Int16(registers)
+		raw = float64(v)
+	case TypeUint32:
+		var v uint32
+		v, err = m.decoder.Uint32(registers)
+		raw = float64(v)
+	case TypeInt32:
+		var v int32
+		v, err = m.decoder.Int32(registers)
+		raw = float64(v)
+	case TypeFloat32:
+		var v float32
+		v, err = m.decoder.Float32(registers)
+		raw = float64(v)
+	case TypeUint64:
+		var v uint64
+		v, err = m.decoder.Uint64(registers)
+		raw = float64(v)
+	case TypeInt64:
+		var v int64
+		v, err = m.decoder.Int64(registers)
+		raw = float64(v)
+	case TypeFloat64:
+		raw, err = m.decoder.Float64(registers)
+	default:
+		err = fmt.Errorf("%w: type %d", ErrInvalidTag, tag.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return raw*tag.scale() + tag.Offset, nil
+}
+
+// WriteTag writes the value of a coil or holding register tag: a bool,
+// a string, or a number in engineering units
+func (m *RegisterMap) WriteTag(name string, value any) error {
+	i, ok := m.byName[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownTag, name)
+	}
+	tag := &m.tags[i]
+
+	switch tag.Table {
+	case TableCoils:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%w: %T value for tag %q", ErrInvalidTag, value, name)
+		}
+		return m.client.WriteSingleCoil(m.slaveID, tag.Address, v)
+	case TableHoldingRegisters:
+		registers, err := m.encode(tag, value)
+		if err != nil {
+			return fmt.Errorf("tag %q: %w", name, err)
+		}
+		if len(registers) == 1 {
+			return m.client.WriteSingleRegister(m.slaveID, tag.Address, registers[0])
+		}
+		return m.client.WriteMultipleRegisters(m.slaveID, tag.Address, registers)
+	}
+	return fmt.Errorf("%w: %q in %s", ErrReadOnlyTag, name, tag.Table)
+}
+
+// encode converts the value of a tag to its registers
+func (m *RegisterMap) encode(tag *Tag, value any) ([]uint16, error) {
+	if tag.Type == TypeString {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T value", ErrInvalidTag, value)
+		}
+		return m.encoder.String(s, tag.Length)
+	}
+
+	v, ok := toFloat64(value)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T value", ErrInvalidTag, value)
+	}
+	raw := (v - tag.Offset) / tag.scale()
+
+	switch tag.Type {
+	case TypeUint16:
+		raw = math.Round(raw)
+		if raw < 0 || raw > math.MaxUint16 {
+			return nil, fmt.Errorf("%w: %v out of range", ErrInvalidTag, value)
+		}
+		return m.encoder.Uint16(uint16(raw)), nil
+	case TypeInt16:
+		raw = math.Round(raw)
+		if raw < math.MinInt16 || raw > math.MaxInt16 {
+			return nil, fmt.Errorf("%w: %v out of range", ErrInvalidTag, value)
+		}
+		return m.encoder.Int16(int16(raw)), nil
+	case TypeUint32:
+		raw = math.Round(raw)
+		if raw < 0 || raw > math.MaxUint32 {
+			return nil, fmt.Errorf("%w: %v out of range", ErrInvalidTag, value)
+		}
+		return m.encoder.Uint32(uint32(raw)), nil
+	case TypeInt32:
+		raw = math.Round(raw)
+		if raw < math.MinInt32 || raw > math.MaxInt32 {
+			return nil, fmt.Errorf("%w: %v out of range", ErrInvalidTag, value)
+		}
+		return m.encoder.Int32(int32(raw)), nil
+	case TypeFloat32:
+		return m.encoder.Float32(float32(raw)), nil
+	case TypeUint64:
+		raw = math.Round(raw)
+		if raw < 0 || raw >= math.MaxUint64 {
+			return nil, fmt.Errorf("%w: %v out of range", ErrInvalidTag, value)
+		}
+		return m.encoder.Uint64(uint64(raw)), nil
+	case TypeInt64:
+		raw = math.Round(raw)
+		if raw < math.MinInt64 || raw >= math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %v out of range", ErrInvalidTag, value)
+		}
+		return m.encoder.Int64(int64(raw)), nil
+	case TypeFloat64:
+		return m.encoder.Float64(raw), nil
+	}
+	return nil, fmt.Errorf("%w: type %d", ErrInvalidTag, tag.Type)
+}
+
+// toFloat64 converts a numeric value to float64
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,80 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// RetryPolicy decides whether failed requests are sent again and when.
+// Retrying writes may execute them twice if only the response was lost.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request may be sent, 1 if zero
+	MaxAttempts int
+	// Backoff computes the wait before each retry, none if nil
+	Backoff Backoff
+	// Errors are retried when matched with errors.Is, ErrTimeout,
+	// ErrInvalidCRC and ErrInvalidLRC if nil
+	Errors []error
+	// Exceptions are the exception codes retried, such as
+	// ExceptionSlaveDeviceBusy
+	Exceptions []byte
+}
+
+// defaultRetryErrors are the errors retried if a policy lists none, the
+// ones a noisy line produces
+var defaultRetryErrors = []error{ErrTimeout, ErrInvalidCRC, ErrInvalidLRC}
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a context whose requests follow policy instead
+// of the policy of the client. A nil policy disables retries.
+func WithRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// retryPolicyFor returns the policy applying to a request made with ctx
+func retryPolicyFor(ctx context.Context, client *RetryPolicy) *RetryPolicy {
+	if policy, ok := ctx.Value(retryPolicyKey{}).(*RetryPolicy); ok {
+		return policy
+	}
+	return client
+}
+
+// retryable reports whether the policy retries err
+func (p *RetryPolicy) retryable(err error) bool {
+	var modbusErr *ModbusError
+	if errors.As(err, &modbusErr) {
+		return slices.Contains(p.Exceptions, modbusErr.ExceptionCode)
+	}
+
+	retryErrors := p.Errors
+	if retryErrors == nil {
+		retryErrors = defaultRetryErrors
+	}
+	for _, target := range retryErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// do sends a request with send until it succeeds, fails with an error the
+// policy does not retry or runs out of attempts
+func (p *RetryPolicy) do(ctx context.Context, send func() ([]byte, error)) ([]byte, error) {
+	response, err := send()
+	if p == nil {
+		return response, err
+	}
+
+	for attempt := 1; attempt < p.MaxAttempts && err != nil && p.retryable(err); attempt++ {
+		if p.Backoff != nil {
+			if err := sleepContext(ctx, p.Backoff.Delay(attempt)); err != nil {
+				return nil, err
+			}
+		}
+		response, err = send()
+	}
+	return response, err
+}
@@ -1,7 +1,7 @@
 package modbus
 
 import (
-	"encoding/binary"
+	"context"
 	"fmt"
 	"time"
 
@@ -10,8 +10,30 @@ import (
 
 // RTUClient implements Modbus RTU client
 type RTUClient struct {
-	config *RTUConfig
-	port   serial.Port
+	*GenericClient
+
+	config    *RTUConfig
+	port      serial.Port
+	writeOnly map[byte]bool
+	crcErrors uint64
+	crcAlarm  crcAlarm
+	capture   *FrameCapture
+}
+
+// CRCErrorBurst describes CRC errors exceeding the alarm threshold
+type CRCErrorBurst struct {
+	Device string
+	Count  int
+	Window time.Duration
+	Time   time.Time
+}
+
+// crcAlarm tracks recent CRC errors to detect bursts
+type crcAlarm struct {
+	threshold int
+	window    time.Duration
+	handler   func(CRCErrorBurst)
+	times     []time.Time
 }
 
 // RTUConfig holds RTU-specific configuration
@@ -23,13 +45,17 @@ type RTUConfig struct {
 	StopBits     serial.StopBits
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	// Checksum of the frames, ModbusCRC16 if nil
+	Checksum Checksum
 }
 
 // NewRTUClient creates a new Modbus RTU client
 func NewRTUClient(config *RTUConfig) *RTUClient {
-	return &RTUClient{
+	c := &RTUClient{
 		config: config,
 	}
+	c.GenericClient = NewGenericClient(c)
+	return c
 }
 
 // Connect opens the serial port
@@ -75,243 +101,251 @@ func (c *RTUClient) SetTimeout(timeout time.Duration) {
 	}
 }
 
-// sendRequest sends a Modbus RTU request
-func (c *RTUClient) sendRequest(slaveID byte, pdu *PDU) ([]byte, error) {
-	if c.port == nil {
-		return nil, fmt.Errorf("port not open")
-	}
-
-	// Build ADU
-	adu := []byte{slaveID, pdu.FunctionCode}
-	adu = append(adu, pdu.Data...)
-	adu = AppendCRC(adu)
-
-	// Send request
-	_, err := c.port.Write(adu)
-	if err != nil {
-		return nil, fmt.Errorf("write failed: %w", err)
+// SetWriteOnly marks a device as reachable over a link that never returns
+// responses. Writes to it return as soon as they are sent and reads fail
+// with ErrWriteOnly.
+func (c *RTUClient) SetWriteOnly(slaveID byte, writeOnly bool) {
+	if c.writeOnly == nil {
+		c.writeOnly = make(map[byte]bool)
 	}
+	c.writeOnly[slaveID] = writeOnly
+}
 
-	// Wait for response (RTU inter-frame delay)
-	time.Sleep(time.Millisecond * 10)
+// CRCErrors returns the number of responses received with an invalid CRC
+func (c *RTUClient) CRCErrors() uint64 {
+	return c.crcErrors
+}
 
-	// Read response - timeout handled by port
-	response := make([]byte, 260) // Max RTU frame size
-	n, err := c.port.Read(response)
-	if err != nil {
-		return nil, fmt.Errorf("read failed: %w", err)
+// SetCRCErrorAlarm calls handler when threshold CRC errors occur within
+// window, which usually means a device changed its serial settings or a
+// node disturbs the bus. The handler runs once per burst, from the
+// goroutine that sent the request; it may reconfigure the port.
+func (c *RTUClient) SetCRCErrorAlarm(threshold int, window time.Duration, handler func(CRCErrorBurst)) {
+	c.crcAlarm = crcAlarm{
+		threshold: threshold,
+		window:    window,
+		handler:   handler,
 	}
+}
 
-	if n < 4 {
-		return nil, ErrTimeout
-	}
+// recordCRCError counts a CRC error and raises the alarm on a burst
+func (c *RTUClient) recordCRCError() {
+	c.crcErrors++
 
-	// Validate CRC
-	if !CheckCRC(response[:n]) {
-		return nil, ErrInvalidCRC
+	a := &c.crcAlarm
+	if a.handler == nil || a.threshold <= 0 {
+		return
 	}
 
-	// Remove CRC and validate slave ID
-	frame := response[:n-2]
-	if frame[0] != slaveID {
-		return nil, ErrInvalidSlaveID
+	now := time.Now()
+	recent := a.times[:0]
+	for _, t := range a.times {
+		if now.Sub(t) < a.window {
+			recent = append(recent, t)
+		}
 	}
+	a.times = append(recent, now)
 
-	// Check for exception
-	if frame[1] == (pdu.FunctionCode | 0x80) {
-		if len(frame) >= 3 {
-			return nil, &ModbusError{
-				FunctionCode:  pdu.FunctionCode,
-				ExceptionCode: frame[2],
-			}
+	if len(a.times) >= a.threshold {
+		burst := CRCErrorBurst{
+			Device: c.config.Device,
+			Count:  len(a.times),
+			Window: a.window,
+			Time:   now,
 		}
-		return nil, ErrInvalidResponse
+		a.times = a.times[:0]
+		a.handler(burst)
 	}
-
-	return frame[2:], nil // Return data without slave ID and function code
 }
 
-// Implement the same methods as TCP client but using RTU protocol
-// ReadCoils, ReadDiscreteInputs, ReadHoldingRegisters, etc.
-// The implementation is identical to TCP except using sendRequest method above
+// SetCapture sends a copy of every frame sent and received to capture,
+// nil stops capturing
+func (c *RTUClient) SetCapture(capture *FrameCapture) {
+	c.capture = capture
+}
 
-func (c *RTUClient) ReadCoils(slaveID byte, address uint16, quantity uint16) ([]bool, error) {
-	if quantity == 0 || quantity > 2000 {
-		return nil, ErrInvalidQuantity
+// checksum returns the checksum used for frames
+func (c *RTUClient) checksum() Checksum {
+	if c.config.Checksum != nil {
+		return c.config.Checksum
 	}
+	return ModbusCRC16
+}
 
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], quantity)
-
-	pdu := &PDU{
-		FunctionCode: FuncCodeReadCoils,
-		Data:         data,
+// WriteFrame sends pdu to slaveID in an RTU frame with its checksum.
+// Together with ReadFrame it allows custom request interleaving.
+func (c *RTUClient) WriteFrame(slaveID byte, pdu *PDU) error {
+	if c.port == nil {
+		return fmt.Errorf("port not open")
 	}
 
-	response, err := c.sendRequest(slaveID, pdu)
-	if err != nil {
-		return nil, err
-	}
+	// Build ADU
+	adu := []byte{slaveID, pdu.FunctionCode}
+	adu = append(adu, pdu.Data...)
+	adu = c.checksum().Append(adu)
 
-	if len(response) < 1 {
-		return nil, ErrInvalidResponse
+	_, err := c.port.Write(adu)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
 	}
 
-	return bytesToBools(response[1:], quantity), nil
+	c.capture.capture(FrameSent, adu)
+	return nil
 }
 
-func (c *RTUClient) ReadDiscreteInputs(slaveID byte, address uint16, quantity uint16) ([]bool, error) {
-	if quantity == 0 || quantity > 2000 {
-		return nil, ErrInvalidQuantity
-	}
-
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], quantity)
+// maxRTUFrame is the length of the largest RTU frame
+const maxRTUFrame = 256
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeReadDiscreteInputs,
-		Data:         data,
-	}
-
-	response, err := c.sendRequest(slaveID, pdu)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(response) < 1 {
-		return nil, ErrInvalidResponse
+// rtuFrameLength returns the length of the response frame starting with
+// head, false while it cannot be determined yet or depends on the silence
+// ending the frame. Function codes are looked up in the registry.
+func rtuFrameLength(head []byte, checksumSize int) (int, bool) {
+	if len(head) < 2 {
+		return 0, false
 	}
 
-	return bytesToBools(response[1:], quantity), nil
+	n, ok := responseLength(head[1:])
+	return 1 + n + checksumSize, ok
 }
 
-func (c *RTUClient) ReadHoldingRegisters(slaveID byte, address uint16, quantity uint16) ([]uint16, error) {
-	if quantity == 0 || quantity > 125 {
-		return nil, ErrInvalidQuantity
+// ReadFrame reads the next RTU frame, verifies its checksum and returns
+// its content without the checksum. The read timeout bounds the wait for
+// the first byte, the frame then ends once its expected length is read or
+// after a silence of 3.5 characters.
+func (c *RTUClient) ReadFrame() (*ADU, error) {
+	if c.port == nil {
+		return nil, fmt.Errorf("port not open")
 	}
 
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], quantity)
+	checksum := c.checksum()
+	frame := make([]byte, 0, maxRTUFrame)
+	buf := make([]byte, maxRTUFrame)
+	for {
+		want := maxRTUFrame - len(frame)
+		if expected, ok := rtuFrameLength(frame, checksum.Size()); ok {
+			want = min(expected, maxRTUFrame) - len(frame)
+		}
+		if want <= 0 {
+			break
+		}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeReadHoldingRegisters,
-		Data:         data,
+		n, err := c.port.Read(buf[:want])
+		if err != nil {
+			return nil, fmt.Errorf("read failed: %w", err)
+		}
+		if n == 0 {
+			// Silence ends the frame
+			break
+		}
+
+		if len(frame) == 0 {
+			c.port.SetReadTimeout(frameGap(c.config.Baud))
+			defer restoreReadTimeout(c.port, c.config.ReadTimeout)
+		}
+		frame = append(frame, buf[:n]...)
 	}
 
-	response, err := c.sendRequest(slaveID, pdu)
-	if err != nil {
-		return nil, err
+	c.capture.capture(FrameReceived, frame)
+
+	if len(frame) < 2+checksum.Size() {
+		return nil, ErrTimeout
 	}
 
-	if len(response) < 1 {
-		return nil, ErrInvalidResponse
+	// Validate checksum
+	if !checksum.Check(frame) {
+		c.recordCRCError()
+		return nil, ErrInvalidCRC
 	}
 
-	return bytesToUint16s(response[1:]), nil
+	// Remove checksum
+	frame = frame[:len(frame)-checksum.Size()]
+	return &ADU{
+		SlaveID: frame[0],
+		PDU: &PDU{
+			FunctionCode: frame[1],
+			Data:         frame[2:],
+		},
+	}, nil
 }
 
-func (c *RTUClient) ReadInputRegisters(slaveID byte, address uint16, quantity uint16) ([]uint16, error) {
-	if quantity == 0 || quantity > 125 {
-		return nil, ErrInvalidQuantity
+// restoreReadTimeout sets the read timeout of a serial port back to the
+// configured one, none if zero
+func restoreReadTimeout(port serial.Port, timeout time.Duration) {
+	if timeout > 0 {
+		port.SetReadTimeout(timeout)
+	} else {
+		port.SetReadTimeout(serial.NoTimeout)
 	}
+}
 
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], quantity)
+// Endpoint returns the serial device
+func (c *RTUClient) Endpoint() string {
+	return c.config.Device
+}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeReadInputRegisters,
-		Data:         data,
+// Send sends a Modbus RTU request once, implementing Transporter. The
+// request is not sent if ctx is already done, and its deadline bounds the
+// wait for the response.
+func (c *RTUClient) Send(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	if c.port == nil {
+		return nil, fmt.Errorf("port not open")
 	}
-
-	response, err := c.sendRequest(slaveID, pdu)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	if len(response) < 1 {
-		return nil, ErrInvalidResponse
+	writeOnly := c.writeOnly[slaveID] || slaveID == BroadcastID
+	if writeOnly && !isWriteFunction(pdu.FunctionCode) {
+		return nil, ErrWriteOnly
 	}
 
-	return bytesToUint16s(response[1:]), nil
-}
-
-func (c *RTUClient) WriteSingleCoil(slaveID byte, address uint16, value bool) error {
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	if value {
-		binary.BigEndian.PutUint16(data[2:4], 0xFF00)
-	} else {
-		binary.BigEndian.PutUint16(data[2:4], 0x0000)
+	meta := readMetaFrom(ctx)
+	meta.sent(0)
+	err := c.WriteFrame(slaveID, pdu)
+	if err != nil {
+		return nil, err
 	}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeWriteSingleCoil,
-		Data:         data,
+	if writeOnly {
+		return nil, nil
 	}
 
-	_, err := c.sendRequest(slaveID, pdu)
-	return err
-}
-
-func (c *RTUClient) WriteSingleRegister(slaveID byte, address uint16, value uint16) error {
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], value)
-
-	pdu := &PDU{
-		FunctionCode: FuncCodeWriteSingleRegister,
-		Data:         data,
+	// Serial reads cannot be interrupted, a deadline shortens the timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if c.config.ReadTimeout <= 0 || timeout < c.config.ReadTimeout {
+			c.port.SetReadTimeout(max(timeout, time.Millisecond))
+			defer restoreReadTimeout(c.port, c.config.ReadTimeout)
+		}
 	}
 
-	_, err := c.sendRequest(slaveID, pdu)
-	return err
-}
-
-func (c *RTUClient) WriteMultipleCoils(slaveID byte, address uint16, values []bool) error {
-	if len(values) == 0 || len(values) > 1968 {
-		return ErrInvalidQuantity
+	adu, err := c.ReadFrame()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
 	}
+	meta.received(time.Now())
 
-	byteCount := (len(values) + 7) / 8
-	data := make([]byte, 5+byteCount)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], uint16(len(values)))
-	data[4] = byte(byteCount)
-
-	coilBytes := boolsToBytes(values)
-	copy(data[5:], coilBytes)
-
-	pdu := &PDU{
-		FunctionCode: FuncCodeWriteMultipleCoils,
-		Data:         data,
+	// Validate slave ID and function code
+	if adu.SlaveID != slaveID {
+		return nil, ErrInvalidSlaveID
 	}
-
-	_, err := c.sendRequest(slaveID, pdu)
-	return err
-}
-
-func (c *RTUClient) WriteMultipleRegisters(slaveID byte, address uint16, values []uint16) error {
-	if len(values) == 0 || len(values) > 123 {
-		return ErrInvalidQuantity
+	if adu.PDU.FunctionCode&0x7F != pdu.FunctionCode {
+		return nil, ErrInvalidResponse
 	}
 
-	data := make([]byte, 5+len(values)*2)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], uint16(len(values)))
-	data[4] = byte(len(values) * 2)
-
-	regBytes := uint16sToBytes(values)
-	copy(data[5:], regBytes)
-
-	pdu := &PDU{
-		FunctionCode: FuncCodeWriteMultipleRegisters,
-		Data:         data,
+	// Check for exception
+	if adu.PDU.FunctionCode == (pdu.FunctionCode | 0x80) {
+		if len(adu.PDU.Data) >= 1 {
+			return nil, &ModbusError{
+				FunctionCode:  pdu.FunctionCode,
+				ExceptionCode: adu.PDU.Data[0],
+			}
+		}
+		return nil, ErrInvalidResponse
 	}
 
-	_, err := c.sendRequest(slaveID, pdu)
-	return err
+	return adu.PDU.Data, nil // Return data without slave ID and function code
 }
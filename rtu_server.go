@@ -0,0 +1,206 @@
+package modbus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// frameGap returns the 3.5 character silence separating RTU frames.
+// Above 19200 baud the specification fixes it at 1.75ms.
+func frameGap(baud int) time.Duration {
+	if baud <= 0 || baud > 19200 {
+		return 1750 * time.Microsecond
+	}
+	// 11 bits per character: start, 8 data, parity or stop, stop
+	return time.Duration(3.5 * 11 * float64(time.Second) / float64(baud))
+}
+
+// RTUServer implements Modbus RTU slave serving a DataStore over a serial port
+type RTUServer struct {
+	config    *RTUConfig
+	slaveID   byte
+	store     DataStore
+	functions FunctionFilter
+
+	mu      sync.Mutex
+	port    serial.Port
+	closed  bool
+	onError func(error)
+}
+
+// NewRTUServer creates a new Modbus RTU slave answering to slaveID
+func NewRTUServer(config *RTUConfig, slaveID byte, store DataStore) *RTUServer {
+	return &RTUServer{
+		config:  config,
+		slaveID: slaveID,
+		store:   store,
+	}
+}
+
+// checksum returns the checksum used for frames
+func (s *RTUServer) checksum() Checksum {
+	if s.config.Checksum != nil {
+		return s.config.Checksum
+	}
+	return ModbusCRC16
+}
+
+// OnError sets a function called with the frames dropped as noise, such
+// as checksum failures, nil discards them
+func (s *RTUServer) OnError(fn func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onError = fn
+}
+
+// reportError passes err to the OnError function
+func (s *RTUServer) reportError(err error) {
+	s.mu.Lock()
+	fn := s.onError
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// Functions returns the filter enabling the function codes served
+func (s *RTUServer) Functions() *FunctionFilter {
+	return &s.functions
+}
+
+// ListenAndServe opens the serial port and answers requests until Close
+// is called, it returns ErrServerClosed after Close
+func (s *RTUServer) ListenAndServe() error {
+	mode := &serial.Mode{
+		BaudRate: s.config.Baud,
+		DataBits: s.config.DataBits,
+		Parity:   s.config.Parity,
+		StopBits: s.config.StopBits,
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+
+	port, err := serial.Open(s.config.Device, mode)
+	if err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to open serial port: %w", err)
+	}
+
+	// A read returning nothing means the line stayed silent for a frame gap
+	err = port.SetReadTimeout(frameGap(s.config.Baud))
+	if err != nil {
+		port.Close()
+		s.mu.Unlock()
+		return fmt.Errorf("failed to set read timeout: %w", err)
+	}
+
+	s.port = port
+	s.mu.Unlock()
+
+	return s.serve(port)
+}
+
+// Close closes the serial port and stops serving
+func (s *RTUServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if s.port != nil {
+		return s.port.Close()
+	}
+	return nil
+}
+
+func (s *RTUServer) serve(port serial.Port) error {
+	buf := make([]byte, 256)
+	frame := make([]byte, 0, 256)
+
+	for {
+		n, err := port.Read(buf)
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return fmt.Errorf("read failed: %w", err)
+		}
+
+		if n > 0 {
+			// Frames longer than the maximum are noise, drop them
+			if len(frame)+n > 256 {
+				frame = frame[:0]
+				s.reportError(fmt.Errorf("%w: frame longer than 256 bytes", ErrInvalidRequest))
+				continue
+			}
+			frame = append(frame, buf[:n]...)
+			continue
+		}
+
+		// Silence ends the frame
+		if len(frame) > 0 {
+			err = s.handleFrame(port, frame)
+			frame = frame[:0]
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleFrame answers a complete request frame
+func (s *RTUServer) handleFrame(port serial.Port, frame []byte) error {
+	checksum := s.checksum()
+	if len(frame) < 2+checksum.Size() {
+		s.reportError(fmt.Errorf("%w: frame of %d bytes", ErrInvalidRequest, len(frame)))
+		return nil
+	}
+	if !checksum.Check(frame) {
+		s.reportError(ErrInvalidCRC)
+		return nil
+	}
+
+	slaveID := frame[0]
+	if slaveID != s.slaveID && slaveID != BroadcastID {
+		return nil
+	}
+
+	request := &PDU{
+		FunctionCode: frame[1],
+		Data:         frame[2 : len(frame)-checksum.Size()],
+	}
+
+	// Only writes make sense as broadcast, and they are never answered
+	if slaveID == BroadcastID {
+		if isWriteFunction(request.FunctionCode) {
+			handleRequest(s.store, &s.functions, s.slaveID, request)
+		}
+		return nil
+	}
+
+	response := handleRequest(s.store, &s.functions, slaveID, request)
+
+	adu := []byte{slaveID, response.FunctionCode}
+	adu = append(adu, response.Data...)
+	adu = checksum.Append(adu)
+
+	_, err := port.Write(adu)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	return nil
+}
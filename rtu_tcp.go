@@ -0,0 +1,282 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// rtuOverTCPGap is the silence ending an RTU frame received over TCP when
+// its length cannot be determined from its content. Serial-to-Ethernet
+// converters forward the bytes of a frame together, but the network
+// blurs the 3.5 character gap of the serial line.
+const rtuOverTCPGap = 50 * time.Millisecond
+
+// RTUOverTCPClient implements Modbus RTU client over a TCP socket, for
+// serial-to-Ethernet converters in raw TCP mode. Frames are RTU frames
+// with their CRC, without MBAP header. Requests are sent one at a time.
+// Without transaction IDs a late response cannot be told apart, so the
+// connection is redialed after a request whose response was not read.
+type RTUOverTCPClient struct {
+	*GenericClient
+
+	address string
+	conn    net.Conn
+	timeout time.Duration
+	crc     Checksum
+	capture *FrameCapture
+	stale   bool // a late response may still arrive on conn
+	mu      sync.Mutex
+}
+
+// NewRTUOverTCPClient creates a new Modbus RTU over TCP client
+func NewRTUOverTCPClient(address string) *RTUOverTCPClient {
+	c := &RTUOverTCPClient{
+		address: address,
+		timeout: 5 * time.Second,
+	}
+	c.GenericClient = NewGenericClient(c)
+	return c
+}
+
+// Connect establishes TCP connection
+func (c *RTUOverTCPClient) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dial()
+}
+
+// dial replaces the connection with a new one, the caller must hold c.mu
+func (c *RTUOverTCPClient) dial() error {
+	conn, err := net.DialTimeout("tcp", c.address, c.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = conn
+	c.stale = false
+	return nil
+}
+
+// Close closes the TCP connection
+func (c *RTUOverTCPClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil {
+		err := c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	return nil
+}
+
+// SetTimeout sets the communication timeout
+func (c *RTUOverTCPClient) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+}
+
+// SetCapture sends a copy of every frame sent and received to capture,
+// nil stops capturing
+func (c *RTUOverTCPClient) SetCapture(capture *FrameCapture) {
+	c.capture = capture
+}
+
+// SetChecksum sets the checksum of the frames, ModbusCRC16 if nil
+func (c *RTUOverTCPClient) SetChecksum(checksum Checksum) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.crc = checksum
+}
+
+// checksum returns the checksum used for frames
+func (c *RTUOverTCPClient) checksum() Checksum {
+	if c.crc != nil {
+		return c.crc
+	}
+	return ModbusCRC16
+}
+
+// WriteFrame sends pdu to slaveID in an RTU frame with its checksum.
+// Together with ReadFrame it allows custom request interleaving.
+func (c *RTUOverTCPClient) WriteFrame(slaveID byte, pdu *PDU) error {
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+
+	// Build ADU
+	adu := []byte{slaveID, pdu.FunctionCode}
+	adu = append(adu, pdu.Data...)
+	adu = c.checksum().Append(adu)
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err := c.conn.Write(adu)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	c.capture.capture(FrameSent, adu)
+	return nil
+}
+
+// ReadFrame reads the next RTU frame, verifies its checksum and returns
+// its content without the checksum. The timeout bounds the wait for the first
+// byte, the frame then ends once its expected length is read.
+func (c *RTUOverTCPClient) ReadFrame() (*ADU, error) {
+	if c.conn == nil {
+		return nil, ErrNotConnected
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	return c.readFrame()
+}
+
+// readFrame reads the next RTU frame, waiting for its first byte until
+// the read deadline of the connection
+func (c *RTUOverTCPClient) readFrame() (*ADU, error) {
+	checksum := c.checksum()
+	frame := make([]byte, 0, maxRTUFrame)
+	buf := make([]byte, maxRTUFrame)
+	for {
+		want := maxRTUFrame - len(frame)
+		if expected, ok := rtuFrameLength(frame, checksum.Size()); ok {
+			want = min(expected, maxRTUFrame) - len(frame)
+		}
+		if want <= 0 {
+			break
+		}
+
+		n, err := c.conn.Read(buf[:want])
+		frame = append(frame, buf[:n]...)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			if len(frame) == 0 {
+				return nil, ErrTimeout
+			}
+			// Silence ends the frame
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read failed: %w", err)
+		}
+
+		c.conn.SetReadDeadline(time.Now().Add(rtuOverTCPGap))
+	}
+
+	c.capture.capture(FrameReceived, frame)
+
+	if len(frame) < 2+checksum.Size() {
+		return nil, ErrTimeout
+	}
+
+	// Validate checksum
+	if !checksum.Check(frame) {
+		return nil, ErrInvalidCRC
+	}
+
+	// Remove checksum
+	frame = frame[:len(frame)-checksum.Size()]
+	return &ADU{
+		SlaveID: frame[0],
+		PDU: &PDU{
+			FunctionCode: frame[1],
+			Data:         frame[2:],
+		},
+	}, nil
+}
+
+// Endpoint returns the address of the server
+func (c *RTUOverTCPClient) Endpoint() string {
+	return c.address
+}
+
+// Send sends a Modbus RTU request over TCP once, implementing
+// Transporter. The request is not sent if ctx is already done, and ctx
+// bounds the wait for the response.
+func (c *RTUOverTCPClient) Send(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil, ErrNotConnected
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	writeOnly := slaveID == BroadcastID
+	if writeOnly && !isWriteFunction(pdu.FunctionCode) {
+		return nil, ErrWriteOnly
+	}
+
+	// The converter drops what was pending on the old connection
+	if c.stale {
+		err := c.dial()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	meta := readMetaFrom(ctx)
+	meta.sent(0)
+	err := c.WriteFrame(slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	if writeOnly {
+		return nil, nil
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	c.conn.SetReadDeadline(deadline)
+
+	// Canceling ctx interrupts the read
+	conn := c.conn
+	stop := interruptRead(ctx, conn)
+	adu, err := c.readFrame()
+	stop()
+	if err != nil {
+		c.stale = true
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	meta.received(time.Now())
+
+	// Validate slave ID and function code, the connection is out of step
+	// with the requests otherwise
+	if adu.SlaveID != slaveID {
+		c.stale = true
+		return nil, ErrInvalidSlaveID
+	}
+	if adu.PDU.FunctionCode&0x7F != pdu.FunctionCode {
+		c.stale = true
+		return nil, ErrInvalidResponse
+	}
+
+	// Check for exception
+	if adu.PDU.FunctionCode == (pdu.FunctionCode | 0x80) {
+		if len(adu.PDU.Data) >= 1 {
+			return nil, &ModbusError{
+				FunctionCode:  pdu.FunctionCode,
+				ExceptionCode: adu.PDU.Data[0],
+			}
+		}
+		return nil, ErrInvalidResponse
+	}
+
+	return adu.PDU.Data, nil // Return data without slave ID and function code
+}
@@ -0,0 +1,136 @@
+package modbus
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serveRTUOverTCP accepts connections and answers each request frame
+// with the frames returned by respond
+func serveRTUOverTCP(t *testing.T, respond func(request []byte) [][]byte) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+
+				buf := make([]byte, maxRTUFrame)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					mu.Lock()
+					frames := respond(append([]byte(nil), buf[:n]...))
+					mu.Unlock()
+					for _, frame := range frames {
+						if frame == nil {
+							time.Sleep(150 * time.Millisecond)
+							continue
+						}
+						conn.Write(frame)
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRTUOverTCPChecksum(t *testing.T) {
+	checksum := CRC16Checksum{Polynomial: 0xA001, Init: 0xFFFF, HighByteFirst: true}
+	address := serveRTUOverTCP(t, func(request []byte) [][]byte {
+		if !checksum.Check(request) {
+			return [][]byte{ModbusCRC16.Append([]byte{1, 0x83, ExceptionSlaveDeviceFailure})}
+		}
+		return [][]byte{checksum.Append([]byte{1, 3, 2, 0x12, 0x34})}
+	})
+
+	c := NewRTUOverTCPClient(address)
+	c.SetChecksum(checksum)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	values, err := c.ReadHoldingRegisters(1, 0, 1)
+	if err != nil || len(values) != 1 || values[0] != 0x1234 {
+		t.Fatalf("ReadHoldingRegisters() = %v, %v, want [4660]", values, err)
+	}
+}
+
+func TestRTUOverTCPLateResponse(t *testing.T) {
+	requests := 0
+	address := serveRTUOverTCP(t, func(request []byte) [][]byte {
+		requests++
+		if requests == 1 {
+			// Answered after the client gave up
+			return [][]byte{nil, ModbusCRC16.Append([]byte{1, 3, 2, 0, 7})}
+		}
+		return [][]byte{ModbusCRC16.Append([]byte{1, 3, 2, 0x12, 0x34})}
+	})
+
+	c := NewRTUOverTCPClient(address)
+	c.SetTimeout(100 * time.Millisecond)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.ReadHoldingRegisters(1, 0, 1); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("ReadHoldingRegisters() = %v, want %v", err, ErrTimeout)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// The late response must not be taken for the response to the next
+	// request
+	values, err := c.ReadHoldingRegisters(1, 0, 1)
+	if err != nil || len(values) != 1 || values[0] != 0x1234 {
+		t.Fatalf("ReadHoldingRegisters() = %v, %v, want [4660]", values, err)
+	}
+}
+
+func TestRTUOverTCPReconnectClosesConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	c := NewRTUOverTCPClient(listener.Addr().String())
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	first, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	first.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := first.Read(make([]byte, 1))
+	if n != 0 || err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("first connection still open: %d, %v", n, err)
+	}
+}
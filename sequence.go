@@ -0,0 +1,118 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SequenceStep writes one or more holding registers as part of a sequence
+type SequenceStep struct {
+	Name    string
+	Address uint16
+	// Values are written with a single register write if there is only
+	// one, with a multiple registers write otherwise
+	Values []uint16
+	// Delay is waited after the step succeeds
+	Delay time.Duration
+	// Retries is the number of extra attempts when the write fails
+	Retries int
+	// RetryDelay is waited between attempts
+	RetryDelay time.Duration
+	// Backoff, if set, computes the wait between attempts instead of RetryDelay
+	Backoff Backoff
+	// Rollback, if set, is called for completed steps in reverse order
+	// when a later step fails
+	Rollback func(client Client, slaveID byte) error
+}
+
+// Sequence is an ordered list of writes such as the unlock, write
+// parameters, commit dance required by many drives
+type Sequence struct {
+	Name  string
+	Steps []SequenceStep
+}
+
+// SequenceError reports the step that stopped a sequence and the errors
+// of the rollbacks run afterwards
+type SequenceError struct {
+	Sequence     string
+	Step         string
+	Err          error
+	RollbackErrs []error
+}
+
+func (e *SequenceError) Error() string {
+	msg := fmt.Sprintf("sequence %q failed at step %q: %v", e.Sequence, e.Step, e.Err)
+	if len(e.RollbackErrs) > 0 {
+		msg += fmt.Sprintf(" (rollback: %v)", errors.Join(e.RollbackErrs...))
+	}
+	return msg
+}
+
+func (e *SequenceError) Unwrap() error {
+	return e.Err
+}
+
+// Run executes the steps in order. When a step fails after its retries,
+// the rollbacks of the completed steps run and a *SequenceError is returned.
+// ctx also bounds the write in flight of clients implementing
+// ContextClient.
+func (s *Sequence) Run(ctx context.Context, client Client, slaveID byte) error {
+	for i, step := range s.Steps {
+		err := s.runStep(ctx, client, slaveID, step)
+		if err == nil {
+			err = sleepContext(ctx, step.Delay)
+		}
+		if err == nil {
+			continue
+		}
+
+		seqErr := &SequenceError{
+			Sequence: s.Name,
+			Step:     step.Name,
+			Err:      err,
+		}
+		for j := i - 1; j >= 0; j-- {
+			if rollback := s.Steps[j].Rollback; rollback != nil {
+				if err := rollback(client, slaveID); err != nil {
+					seqErr.RollbackErrs = append(seqErr.RollbackErrs,
+						fmt.Errorf("step %q: %w", s.Steps[j].Name, err))
+				}
+			}
+		}
+		return seqErr
+	}
+	return nil
+}
+
+func (s *Sequence) runStep(ctx context.Context, client Client, slaveID byte, step SequenceStep) error {
+	var err error
+	for attempt := 0; attempt <= step.Retries; attempt++ {
+		if attempt > 0 {
+			delay := step.RetryDelay
+			if step.Backoff != nil {
+				delay = step.Backoff.Delay(attempt)
+			}
+			if err := sleepContext(ctx, delay); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		switch len(step.Values) {
+		case 0:
+			return ErrInvalidQuantity
+		case 1:
+			err = writeSingleRegister(ctx, client, slaveID, step.Address, step.Values[0])
+		default:
+			err = writeMultipleRegisters(ctx, client, slaveID, step.Address, step.Values)
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+package modbus
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSequenceCancelsRequestInFlight(t *testing.T) {
+	c := connectSilentServer(t)
+	sequence := &Sequence{
+		Name: "start pump",
+		Steps: []SequenceStep{
+			{Name: "speed", Address: 10, Values: []uint16{1500, 0}},
+		},
+	}
+	assertCanceledInFlight(t, func(ctx context.Context) error {
+		return sequence.Run(ctx, c, 1)
+	})
+}
@@ -0,0 +1,281 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// DataStore holds the coils and registers served by a server. Errors are
+// returned to the client as exceptions: ErrInvalidAddress as
+// IllegalDataAddress, ErrInvalidQuantity as IllegalDataValue, an
+// exception error such as ErrSlaveDeviceBusy with its exception code and
+// anything else as SlaveDeviceFailure.
+type DataStore interface {
+	ReadCoils(unitID byte, address uint16, quantity uint16) ([]bool, error)
+	ReadDiscreteInputs(unitID byte, address uint16, quantity uint16) ([]bool, error)
+	ReadHoldingRegisters(unitID byte, address uint16, quantity uint16) ([]uint16, error)
+	ReadInputRegisters(unitID byte, address uint16, quantity uint16) ([]uint16, error)
+	WriteCoils(unitID byte, address uint16, values []bool) error
+	WriteHoldingRegisters(unitID byte, address uint16, values []uint16) error
+}
+
+// MemoryStore is a DataStore keeping the full address space of every table
+// in memory, shared by all unit IDs
+type MemoryStore struct {
+	mu               sync.RWMutex
+	coils            []bool
+	discreteInputs   []bool
+	holdingRegisters []uint16
+	inputRegisters   []uint16
+}
+
+// NewMemoryStore creates a memory store with all values cleared
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		coils:            make([]bool, 0x10000),
+		discreteInputs:   make([]bool, 0x10000),
+		holdingRegisters: make([]uint16, 0x10000),
+		inputRegisters:   make([]uint16, 0x10000),
+	}
+}
+
+func (s *MemoryStore) ReadCoils(unitID byte, address uint16, quantity uint16) ([]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return readTable(s.coils, address, quantity)
+}
+
+func (s *MemoryStore) ReadDiscreteInputs(unitID byte, address uint16, quantity uint16) ([]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return readTable(s.discreteInputs, address, quantity)
+}
+
+func (s *MemoryStore) ReadHoldingRegisters(unitID byte, address uint16, quantity uint16) ([]uint16, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return readTable(s.holdingRegisters, address, quantity)
+}
+
+func (s *MemoryStore) ReadInputRegisters(unitID byte, address uint16, quantity uint16) ([]uint16, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return readTable(s.inputRegisters, address, quantity)
+}
+
+func (s *MemoryStore) WriteCoils(unitID byte, address uint16, values []bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeTable(s.coils, address, values)
+}
+
+func (s *MemoryStore) WriteHoldingRegisters(unitID byte, address uint16, values []uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeTable(s.holdingRegisters, address, values)
+}
+
+// WriteDiscreteInputs sets discrete inputs, which clients can only read
+func (s *MemoryStore) WriteDiscreteInputs(address uint16, values []bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeTable(s.discreteInputs, address, values)
+}
+
+// WriteInputRegisters sets input registers, which clients can only read
+func (s *MemoryStore) WriteInputRegisters(address uint16, values []uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeTable(s.inputRegisters, address, values)
+}
+
+func readTable[T any](table []T, address uint16, quantity uint16) ([]T, error) {
+	end := int(address) + int(quantity)
+	if end > len(table) {
+		return nil, ErrInvalidAddress
+	}
+
+	result := make([]T, quantity)
+	copy(result, table[address:end])
+	return result, nil
+}
+
+func writeTable[T any](table []T, address uint16, values []T) error {
+	if int(address)+len(values) > len(table) {
+		return ErrInvalidAddress
+	}
+
+	copy(table[address:], values)
+	return nil
+}
+
+// FunctionFilter enables and disables the function codes served, to harden
+// exposed endpoints. Disabled function codes are answered with an
+// IllegalFunction exception. Everything is enabled by default.
+type FunctionFilter struct {
+	mu       sync.RWMutex
+	disabled [256]bool
+}
+
+// SetEnabled enables or disables function codes
+func (f *FunctionFilter) SetEnabled(enabled bool, functionCodes ...byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, functionCode := range functionCodes {
+		f.disabled[functionCode] = !enabled
+	}
+}
+
+// SetReadOnly disables or enables every function code writing data
+func (f *FunctionFilter) SetReadOnly(readOnly bool) {
+	f.SetEnabled(!readOnly, FuncCodeWriteSingleCoil, FuncCodeWriteSingleRegister,
+		FuncCodeWriteMultipleCoils, FuncCodeWriteMultipleRegisters,
+		FuncCodeMaskWriteRegister, FuncCodeReadWriteMultipleRegisters)
+}
+
+// Enabled reports whether a function code is served
+func (f *FunctionFilter) Enabled(functionCode byte) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return !f.disabled[functionCode]
+}
+
+// handleRequest executes a request PDU against store and returns the
+// response PDU, an exception response if the request fails or its
+// function code is disabled by filter
+func handleRequest(store DataStore, filter *FunctionFilter, unitID byte, pdu *PDU) *PDU {
+	var data []byte
+	err := error(ErrIllegalFunction)
+	if filter.Enabled(pdu.FunctionCode) {
+		data, err = executeRequest(store, unitID, pdu)
+	}
+	if err != nil {
+		return &PDU{
+			FunctionCode: pdu.FunctionCode | 0x80,
+			Data:         []byte{exceptionCode(err)},
+		}
+	}
+
+	return &PDU{
+		FunctionCode: pdu.FunctionCode,
+		Data:         data,
+	}
+}
+
+// exceptionCode maps a data store error to the exception sent to the client
+func exceptionCode(err error) byte {
+	var modbusErr *ModbusError
+	switch {
+	case errors.As(err, &modbusErr):
+		return modbusErr.ExceptionCode
+	case errors.Is(err, ErrInvalidAddress):
+		return ExceptionIllegalDataAddress
+	case errors.Is(err, ErrInvalidQuantity):
+		return ExceptionIllegalDataValue
+	}
+	return ExceptionSlaveDeviceFailure
+}
+
+// executeRequest validates and executes a request, returning the response data
+func executeRequest(store DataStore, unitID byte, pdu *PDU) ([]byte, error) {
+	err := ValidateRequest(pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	// ValidateRequest checked the length of the data for every case
+	data := pdu.Data
+
+	switch pdu.FunctionCode {
+	case FuncCodeReadCoils, FuncCodeReadDiscreteInputs:
+		address := binary.BigEndian.Uint16(data[0:2])
+		quantity := binary.BigEndian.Uint16(data[2:4])
+
+		var values []bool
+		if pdu.FunctionCode == FuncCodeReadCoils {
+			values, err = store.ReadCoils(unitID, address, quantity)
+		} else {
+			values, err = store.ReadDiscreteInputs(unitID, address, quantity)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		coilBytes := boolsToBytes(values)
+		return append([]byte{byte(len(coilBytes))}, coilBytes...), nil
+
+	case FuncCodeReadHoldingRegisters, FuncCodeReadInputRegisters:
+		address := binary.BigEndian.Uint16(data[0:2])
+		quantity := binary.BigEndian.Uint16(data[2:4])
+
+		var values []uint16
+		if pdu.FunctionCode == FuncCodeReadHoldingRegisters {
+			values, err = store.ReadHoldingRegisters(unitID, address, quantity)
+		} else {
+			values, err = store.ReadInputRegisters(unitID, address, quantity)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		regBytes := uint16sToBytes(values)
+		return append([]byte{byte(len(regBytes))}, regBytes...), nil
+
+	case FuncCodeWriteSingleCoil:
+		address := binary.BigEndian.Uint16(data[0:2])
+		value := binary.BigEndian.Uint16(data[2:4])
+		err = store.WriteCoils(unitID, address, []bool{value == 0xFF00})
+		if err != nil {
+			return nil, err
+		}
+		return data, nil
+
+	case FuncCodeWriteSingleRegister:
+		address := binary.BigEndian.Uint16(data[0:2])
+		value := binary.BigEndian.Uint16(data[2:4])
+		err = store.WriteHoldingRegisters(unitID, address, []uint16{value})
+		if err != nil {
+			return nil, err
+		}
+		return data, nil
+
+	case FuncCodeWriteMultipleCoils:
+		address := binary.BigEndian.Uint16(data[0:2])
+		quantity := binary.BigEndian.Uint16(data[2:4])
+		err = store.WriteCoils(unitID, address, bytesToBools(data[5:], quantity))
+		if err != nil {
+			return nil, err
+		}
+		return data[0:4], nil
+
+	case FuncCodeWriteMultipleRegisters:
+		address := binary.BigEndian.Uint16(data[0:2])
+		err = store.WriteHoldingRegisters(unitID, address, bytesToUint16s(data[5:]))
+		if err != nil {
+			return nil, err
+		}
+		return data[0:4], nil
+
+	case FuncCodeReadWriteMultipleRegisters:
+		address := binary.BigEndian.Uint16(data[0:2])
+		readQuantity := binary.BigEndian.Uint16(data[2:4])
+		writeAddress := binary.BigEndian.Uint16(data[4:6])
+
+		// The write is performed before the read
+		err = store.WriteHoldingRegisters(unitID, writeAddress, bytesToUint16s(data[9:]))
+		if err != nil {
+			return nil, err
+		}
+		values, err := store.ReadHoldingRegisters(unitID, address, readQuantity)
+		if err != nil {
+			return nil, err
+		}
+
+		regBytes := uint16sToBytes(values)
+		return append([]byte{byte(len(regBytes))}, regBytes...), nil
+	}
+
+	return nil, ErrIllegalFunction
+}
@@ -0,0 +1,83 @@
+package modbus
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func startTCPServer(t *testing.T) *TCPServer {
+	t.Helper()
+
+	s := NewTCPServer("127.0.0.1:0", NewMemoryStore())
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve()
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestTCPServerMalformedRequests(t *testing.T) {
+	s := startTCPServer(t)
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	tests := []struct {
+		name     string
+		request  []byte
+		response []byte
+	}{
+		{
+			"get comm event counter without data",
+			[]byte{0, 1, 0, 0, 0, 2, 1, 0x0B},
+			[]byte{0, 1, 0, 0, 0, 3, 1, 0x8B, ExceptionIllegalFunction},
+		},
+		{
+			"diagnostics without data",
+			[]byte{0, 2, 0, 0, 0, 2, 1, 0x08},
+			[]byte{0, 2, 0, 0, 0, 3, 1, 0x88, ExceptionIllegalFunction},
+		},
+		{
+			"read holding registers without data",
+			[]byte{0, 3, 0, 0, 0, 2, 1, 0x03},
+			[]byte{0, 3, 0, 0, 0, 3, 1, 0x83, ExceptionIllegalDataValue},
+		},
+		{
+			"write single register with a short value",
+			[]byte{0, 4, 0, 0, 0, 5, 1, 0x06, 0, 1, 0},
+			[]byte{0, 4, 0, 0, 0, 3, 1, 0x86, ExceptionIllegalDataValue},
+		},
+		{
+			"read past the address space",
+			[]byte{0, 5, 0, 0, 0, 6, 1, 0x03, 0xFF, 0xF0, 0, 100},
+			[]byte{0, 5, 0, 0, 0, 3, 1, 0x83, ExceptionIllegalDataAddress},
+		},
+		{
+			"valid request after the malformed ones",
+			[]byte{0, 6, 0, 0, 0, 6, 1, 0x03, 0, 0, 0, 1},
+			[]byte{0, 6, 0, 0, 0, 5, 1, 0x03, 2, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		if _, err := conn.Write(tt.request); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		response := make([]byte, len(tt.response))
+		if _, err := io.ReadFull(conn, response); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !bytes.Equal(response, tt.response) {
+			t.Errorf("%s: response % X, want % X", tt.name, response, tt.response)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets
+var LatencyBuckets = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second,
+}
+
+// LatencyKey identifies the requests of a latency histogram
+type LatencyKey struct {
+	// Endpoint is the server address or serial device of the client
+	Endpoint     string
+	UnitID       byte
+	FunctionCode byte
+}
+
+// LatencyHistogram holds the latencies of the requests answered by a
+// device, exceptions included. Requests without response only count as
+// errors.
+type LatencyHistogram struct {
+	Count  uint64
+	Errors uint64
+	Sum    time.Duration
+	Min    time.Duration
+	Max    time.Duration
+	// Buckets counts the latencies up to each of LatencyBuckets, the last
+	// bucket those above
+	Buckets []uint64
+}
+
+// Mean returns the mean latency
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns the upper bound of the bucket holding quantile q of
+// the latencies, Max for the last bucket
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(h.Count))
+	var seen uint64
+	for i, count := range h.Buckets {
+		seen += count
+		if seen > rank && i < len(LatencyBuckets) {
+			return min(LatencyBuckets[i], h.Max)
+		}
+	}
+	return h.Max
+}
+
+// add records a latency
+func (h *LatencyHistogram) add(latency time.Duration) {
+	if h.Count == 0 || latency < h.Min {
+		h.Min = latency
+	}
+	h.Max = max(h.Max, latency)
+	h.Count++
+	h.Sum += latency
+
+	bucket := len(LatencyBuckets)
+	for i, bound := range LatencyBuckets {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	h.Buckets[bucket]++
+}
+
+// merge adds the counts of other
+func (h *LatencyHistogram) merge(other *LatencyHistogram) {
+	if other.Count > 0 {
+		if h.Count == 0 || other.Min < h.Min {
+			h.Min = other.Min
+		}
+		h.Max = max(h.Max, other.Max)
+	}
+	h.Count += other.Count
+	h.Errors += other.Errors
+	h.Sum += other.Sum
+	for i := range h.Buckets {
+		h.Buckets[i] += other.Buckets[i]
+	}
+}
+
+// newLatencyHistogram creates an empty histogram
+func newLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{Buckets: make([]uint64, len(LatencyBuckets)+1)}
+}
+
+// LatencyStats collects request latencies per endpoint, unit ID and
+// function code. A client records every attempt of a request in the stats
+// set with SetLatencyStats; several clients can share them.
+type LatencyStats struct {
+	mu         sync.Mutex
+	histograms map[LatencyKey]*LatencyHistogram
+}
+
+// NewLatencyStats creates empty latency stats
+func NewLatencyStats() *LatencyStats {
+	return &LatencyStats{
+		histograms: make(map[LatencyKey]*LatencyHistogram),
+	}
+}
+
+// Histograms returns a copy of the histogram of every endpoint, unit ID
+// and function code seen
+func (s *LatencyStats) Histograms() map[LatencyKey]LatencyHistogram {
+	return s.collect(func(LatencyKey) (LatencyKey, bool) {
+		return LatencyKey{}, false
+	})
+}
+
+// ByFunctionCode returns the histograms merged per function code
+func (s *LatencyStats) ByFunctionCode() map[byte]LatencyHistogram {
+	merged := s.collect(func(key LatencyKey) (LatencyKey, bool) {
+		return LatencyKey{FunctionCode: key.FunctionCode}, true
+	})
+
+	result := make(map[byte]LatencyHistogram, len(merged))
+	for key, h := range merged {
+		result[key.FunctionCode] = h
+	}
+	return result
+}
+
+// ByEndpoint returns the histograms merged per endpoint
+func (s *LatencyStats) ByEndpoint() map[string]LatencyHistogram {
+	merged := s.collect(func(key LatencyKey) (LatencyKey, bool) {
+		return LatencyKey{Endpoint: key.Endpoint}, true
+	})
+
+	result := make(map[string]LatencyHistogram, len(merged))
+	for key, h := range merged {
+		result[key.Endpoint] = h
+	}
+	return result
+}
+
+// ByUnit returns the histograms merged per unit ID, the units of the same
+// ID on several endpoints together
+func (s *LatencyStats) ByUnit() map[byte]LatencyHistogram {
+	merged := s.collect(func(key LatencyKey) (LatencyKey, bool) {
+		return LatencyKey{UnitID: key.UnitID}, true
+	})
+
+	result := make(map[byte]LatencyHistogram, len(merged))
+	for key, h := range merged {
+		result[key.UnitID] = h
+	}
+	return result
+}
+
+// Total returns the histogram of all requests
+func (s *LatencyStats) Total() LatencyHistogram {
+	merged := s.collect(func(LatencyKey) (LatencyKey, bool) {
+		return LatencyKey{}, true
+	})
+	if h, ok := merged[LatencyKey{}]; ok {
+		return h
+	}
+	return *newLatencyHistogram()
+}
+
+// Reset clears all histograms
+func (s *LatencyStats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.histograms)
+}
+
+// collect copies the histograms, merging those mapped to the same key
+// by group if it reports true
+func (s *LatencyStats) collect(group func(key LatencyKey) (LatencyKey, bool)) map[LatencyKey]LatencyHistogram {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	merged := make(map[LatencyKey]*LatencyHistogram)
+	for key, h := range s.histograms {
+		if grouped, ok := group(key); ok {
+			key = grouped
+		}
+		if merged[key] == nil {
+			merged[key] = newLatencyHistogram()
+		}
+		merged[key].merge(h)
+	}
+
+	result := make(map[LatencyKey]LatencyHistogram, len(merged))
+	for key, h := range merged {
+		result[key] = *h
+	}
+	return result
+}
+
+// timed runs an exchange and records its latency, or an error if no
+// response arrived. Canceled requests are not recorded.
+func (s *LatencyStats) timed(endpoint string, unitID byte, functionCode byte, exchange func() ([]byte, error)) ([]byte, error) {
+	if s == nil {
+		return exchange()
+	}
+
+	start := time.Now()
+	response, err := exchange()
+	latency := time.Since(start)
+
+	if errors.Is(err, context.Canceled) {
+		return response, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := LatencyKey{Endpoint: endpoint, UnitID: unitID, FunctionCode: functionCode}
+	h := s.histograms[key]
+	if h == nil {
+		h = newLatencyHistogram()
+		s.histograms[key] = h
+	}
+
+	var modbusErr *ModbusError
+	if err == nil || errors.As(err, &modbusErr) {
+		h.add(latency)
+	} else {
+		h.Errors++
+	}
+	return response, err
+}
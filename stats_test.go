@@ -0,0 +1,44 @@
+package modbus
+
+import "testing"
+
+// The same unit behind two gateways has a histogram per gateway
+func TestLatencyStatsSharedByClients(t *testing.T) {
+	stats := NewLatencyStats()
+
+	var endpoints []string
+	for requests := range 3 {
+		s := startTCPServer(t)
+		c := NewTCPClient(s.Addr().String())
+		c.SetLatencyStats(stats)
+		if err := c.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		for range requests + 1 {
+			if _, err := c.ReadHoldingRegisters(1, 0, 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		endpoints = append(endpoints, c.Endpoint())
+	}
+
+	histograms := stats.Histograms()
+	if len(histograms) != len(endpoints) {
+		t.Fatalf("got %d histograms, want %d", len(histograms), len(endpoints))
+	}
+	byEndpoint := stats.ByEndpoint()
+	for i, endpoint := range endpoints {
+		key := LatencyKey{Endpoint: endpoint, UnitID: 1, FunctionCode: FuncCodeReadHoldingRegisters}
+		if h := histograms[key]; h.Count != uint64(i+1) {
+			t.Errorf("%v: %d requests, want %d", key, h.Count, i+1)
+		}
+		if h := byEndpoint[endpoint]; h.Count != uint64(i+1) {
+			t.Errorf("endpoint %s: %d requests, want %d", endpoint, h.Count, i+1)
+		}
+	}
+	if h := stats.ByUnit()[1]; h.Count != 6 {
+		t.Errorf("unit 1: %d requests, want 6", h.Count)
+	}
+}
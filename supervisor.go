@@ -0,0 +1,251 @@
+package modbus
+
+import (
+	"sync"
+	"time"
+)
+
+// SupervisorState is the state of a supervised connection
+type SupervisorState int
+
+const (
+	// StateDisconnected is the initial state, no connection is open
+	StateDisconnected SupervisorState = iota
+	// StateConnecting means a dial is in progress
+	StateConnecting
+	// StateConnected means the connection is usable
+	StateConnected
+	// StateBackoff means the last dial failed and the next one must wait
+	StateBackoff
+	// StateClosed means the connection was closed on purpose
+	StateClosed
+)
+
+func (s SupervisorState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateBackoff:
+		return "backoff"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// SupervisorEvent describes a state transition
+type SupervisorEvent struct {
+	From SupervisorState
+	To   SupervisorState
+	// Err is the error that caused the transition, if any
+	Err  error
+	Time time.Time
+}
+
+// Supervisor is the connection state machine used by clients to connect,
+// detect failures and reconnect. It can also drive custom transports:
+// Connect dials, Fail reports a broken connection and Close stops it.
+//
+//	Disconnected -> Connecting -> Connected -> Disconnected (Fail)
+//	                Connecting -> Backoff -> Connecting (after retry delay)
+//	any state -> Closed (Close), Closed -> Disconnected (Reset)
+type Supervisor struct {
+	dial func() error
+
+	mu          sync.Mutex
+	state       SupervisorState
+	attempts    int
+	backoff     Backoff
+	nextAttempt time.Time
+	onEvent     func(SupervisorEvent)
+	stats       SupervisorStats
+}
+
+// SupervisorStats holds counters of a supervisor
+type SupervisorStats struct {
+	State SupervisorState
+	// Backoff names the strategy used between failed dials
+	Backoff string
+	// Attempts is the number of failed dials since the last success
+	Attempts    int
+	Dials       uint64
+	DialErrors  uint64
+	Failures    uint64 // connections reported broken
+	NextAttempt time.Time
+}
+
+// NewSupervisor creates a supervisor using dial to open the connection
+func NewSupervisor(dial func() error) *Supervisor {
+	return &Supervisor{
+		dial:    dial,
+		backoff: ConstantBackoff{Interval: time.Second},
+	}
+}
+
+// State returns the current state
+func (s *Supervisor) State() SupervisorState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Attempts returns the number of failed dials since the last success
+func (s *Supervisor) Attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts
+}
+
+// Stats returns the counters of the supervisor
+func (s *Supervisor) Stats() SupervisorStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.State = s.state
+	stats.Backoff = s.backoff.String()
+	stats.Attempts = s.attempts
+	stats.NextAttempt = s.nextAttempt
+	return stats
+}
+
+// SetBackoff sets the strategy computing the wait after failed dials
+func (s *Supervisor) SetBackoff(backoff Backoff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backoff = backoff
+}
+
+// SetRetryDelay sets a constant wait after failed dials
+func (s *Supervisor) SetRetryDelay(delay time.Duration) {
+	s.SetBackoff(ConstantBackoff{Interval: delay})
+}
+
+// OnEvent sets a callback called after every state transition. It runs
+// without any lock of the supervisor or its client held, so it may call
+// them.
+func (s *Supervisor) OnEvent(fn func(SupervisorEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onEvent = fn
+}
+
+// Connect dials unless already connected. While in backoff it fails with
+// ErrReconnectBackoff until the retry delay has elapsed.
+func (s *Supervisor) Connect() error {
+	s.mu.Lock()
+	switch s.state {
+	case StateConnected:
+		s.mu.Unlock()
+		return nil
+	case StateConnecting:
+		s.mu.Unlock()
+		return ErrConnectInProgress
+	case StateClosed:
+		s.mu.Unlock()
+		return ErrSupervisorClosed
+	case StateBackoff:
+		if time.Now().Before(s.nextAttempt) {
+			s.mu.Unlock()
+			return ErrReconnectBackoff
+		}
+	}
+	event := s.transition(StateConnecting, nil)
+	s.stats.Dials++
+	s.mu.Unlock()
+	s.emit(event)
+
+	err := s.dial()
+
+	s.mu.Lock()
+	if s.state != StateConnecting {
+		// Closed while dialing
+		s.mu.Unlock()
+		return ErrSupervisorClosed
+	}
+	if err != nil {
+		s.attempts++
+		s.stats.DialErrors++
+		s.nextAttempt = time.Now().Add(s.backoff.Delay(s.attempts))
+		event = s.transition(StateBackoff, err)
+	} else {
+		s.attempts = 0
+		event = s.transition(StateConnected, nil)
+	}
+	s.mu.Unlock()
+	s.emit(event)
+
+	return err
+}
+
+// Fail reports that the connection broke, it is ignored unless connected
+func (s *Supervisor) Fail(err error) {
+	if event, ok := s.fail(err); ok {
+		s.emit(event)
+	}
+}
+
+// fail is Fail leaving the event to emit to the caller, which may hold
+// the lock of its transport meanwhile
+func (s *Supervisor) fail(err error) (SupervisorEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != StateConnected {
+		return SupervisorEvent{}, false
+	}
+	s.stats.Failures++
+	return s.transition(StateDisconnected, err), true
+}
+
+// Close stops the supervisor, Connect fails until Reset is called
+func (s *Supervisor) Close() {
+	s.mu.Lock()
+	if s.state == StateClosed {
+		s.mu.Unlock()
+		return
+	}
+	event := s.transition(StateClosed, nil)
+	s.mu.Unlock()
+	s.emit(event)
+}
+
+// Reset returns to the disconnected state, cancelling any backoff
+func (s *Supervisor) Reset() {
+	s.mu.Lock()
+	s.attempts = 0
+	if s.state != StateBackoff && s.state != StateClosed {
+		s.mu.Unlock()
+		return
+	}
+	event := s.transition(StateDisconnected, nil)
+	s.mu.Unlock()
+	s.emit(event)
+}
+
+// transition changes the state, the caller must hold s.mu
+func (s *Supervisor) transition(to SupervisorState, err error) SupervisorEvent {
+	event := SupervisorEvent{
+		From: s.state,
+		To:   to,
+		Err:  err,
+		Time: time.Now(),
+	}
+	s.state = to
+	return event
+}
+
+// emit calls the event callback, the caller must not hold s.mu
+func (s *Supervisor) emit(event SupervisorEvent) {
+	s.mu.Lock()
+	fn := s.onEvent
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn(event)
+	}
+}
@@ -1,301 +1,974 @@
 package modbus
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"net"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
+// ProtocolIDPolicy controls how the protocol ID of a response is validated
+type ProtocolIDPolicy int
+
+const (
+	// ProtocolIDStrict rejects responses whose protocol ID differs from the request
+	ProtocolIDStrict ProtocolIDPolicy = iota
+	// ProtocolIDIgnore accepts responses with any protocol ID
+	ProtocolIDIgnore
+)
+
+// TransactionIDStrategy chooses how transaction IDs are generated
+type TransactionIDStrategy int
+
+const (
+	// TransactionIDIncrement increments the ID with every request
+	TransactionIDIncrement TransactionIDStrategy = iota
+	// TransactionIDRandom picks a random ID for every request
+	TransactionIDRandom
+	// TransactionIDConstant always uses the same ID, for devices that
+	// echo a fixed value
+	TransactionIDConstant
+)
+
 // TCPClient implements Modbus TCP client
 type TCPClient struct {
-	address       string
-	conn          net.Conn
-	timeout       time.Duration
-	transactionID uint32
+	*GenericClient
+
+	address          string
+	tlsConfig        *tls.Config
+	conn             net.Conn
+	timeout          time.Duration
+	transactionID    uint32
+	transIDStrategy  TransactionIDStrategy
+	protocolID       uint16
+	protocolIDPolicy ProtocolIDPolicy
+	lenientUnitID    bool
+	writer           frameWriter
+	keepAlive        time.Duration
+	probeInterval    time.Duration
+	probeUnitID      byte
+	probePDU         *PDU
+	probeStop        chan struct{}
+	lastReceived     time.Time // last frame received or dial
+	writeOnly        map[byte]bool
+	supervisor       *Supervisor
+	capture          *FrameCapture
+	window           int
+	windowFallback   bool
+	slots            chan struct{}
+	pending          map[uint16]chan tcpResult
+	abandoned        map[uint16]time.Time
+	custom           map[uint16]bool
+	onError          func(error)
+	reconnectRetries int
+	onReconnect      func()
+	events           []SupervisorEvent // emitted once c.mu is released
+	unclaimed        chan tcpFrame
+	mu               sync.Mutex
+}
+
+// tcpFrame is a frame received by the connection reader
+type tcpFrame struct {
+	transID  uint16
+	protoID  uint16
+	adu      *ADU
+	received time.Time
+}
+
+// tcpResult hands a response or a connection error to a waiting request
+type tcpResult struct {
+	frame tcpFrame
+	err   error
+}
+
+// frameWriter writes MBAP framed requests with a single vectored write,
+// reusing its buffers between requests
+type frameWriter struct {
+	header [8]byte // MBAP header followed by the function code
+	vec    [2][]byte
+}
+
+// writeTo writes the MBAP header and PDU to conn
+func (w *frameWriter) writeTo(conn net.Conn, transID, protocolID uint16, unitID byte, pdu *PDU) error {
+	binary.BigEndian.PutUint16(w.header[0:2], transID)                 // Transaction ID
+	binary.BigEndian.PutUint16(w.header[2:4], protocolID)              // Protocol ID
+	binary.BigEndian.PutUint16(w.header[4:6], uint16(2+len(pdu.Data))) // Length
+	w.header[6] = unitID                                               // Unit ID
+	w.header[7] = pdu.FunctionCode
+
+	w.vec[0] = w.header[:]
+	w.vec[1] = pdu.Data
+	bufs := net.Buffers(w.vec[:])
+
+	// WriteTo keeps writing until every buffer is consumed or an error occurs
+	_, err := bufs.WriteTo(conn)
+	w.vec[1] = nil
+	return err
 }
 
 // NewTCPClient creates a new Modbus TCP client
 func NewTCPClient(address string) *TCPClient {
-	return &TCPClient{
-		address: address,
-		timeout: 5 * time.Second,
-	}
+	c := &TCPClient{
+		address:   address,
+		timeout:   5 * time.Second,
+		window:    1,
+		slots:     make(chan struct{}, 1),
+		pending:   make(map[uint16]chan tcpResult),
+		abandoned: make(map[uint16]time.Time),
+		custom:    make(map[uint16]bool),
+		unclaimed: make(chan tcpFrame, 16),
+		// Unit 0xFF addresses the server itself
+		probeUnitID: 0xFF,
+		probePDU: &PDU{
+			FunctionCode: FuncCodeReadHoldingRegisters,
+			Data:         []byte{0, 0, 0, 1},
+		},
+	}
+	c.GenericClient = NewGenericClient(c)
+	c.supervisor = NewSupervisor(c.dial)
+	return c
+}
+
+// NewTLSClient creates a new Modbus/TCP Security client, exchanging
+// requests over TLS with the settings of config: client certificate, CA
+// pool and server name. Secure devices usually listen on port 802.
+func NewTLSClient(address string, config *tls.Config) *TCPClient {
+	c := NewTCPClient(address)
+	c.tlsConfig = config
+	return c
+}
+
+// Supervisor returns the state machine supervising the connection
+func (c *TCPClient) Supervisor() *Supervisor {
+	return c.supervisor
 }
 
 // Connect establishes TCP connection
 func (c *TCPClient) Connect() error {
-	conn, err := net.DialTimeout("tcp", c.address, c.timeout)
+	c.supervisor.Reset()
+	err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.probeInterval > 0 && c.probeStop == nil {
+		c.probeStop = make(chan struct{})
+		go c.probe(c.probeInterval, c.probeStop)
+	}
+	return nil
+}
+
+// connect dials through the supervisor unless already connected, the
+// caller must not hold c.mu
+func (c *TCPClient) connect() error {
+	err := c.supervisor.Connect()
+	if err != nil {
+		return err
+	}
+
+	// The connection may break before the supervisor sees it connected,
+	// the failure is then reported again
+	c.mu.Lock()
+	lost := c.conn == nil
+	c.mu.Unlock()
+	if lost {
+		c.supervisor.Fail(ErrNotConnected)
+		return c.supervisor.Connect()
+	}
+	return nil
+}
+
+// dial opens a new connection without holding c.mu meanwhile, as the
+// dial may take up to the timeout
+func (c *TCPClient) dial() error {
+	c.mu.Lock()
+	dialer := net.Dialer{
+		Timeout:   c.timeout,
+		KeepAlive: c.keepAlive,
+	}
+	address := c.address
+	tlsConfig := c.tlsConfig
+	c.mu.Unlock()
+
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		// The timeout covers the handshake too
+		tlsDialer := tls.Dialer{NetDialer: &dialer, Config: tlsConfig}
+		conn, err = tlsDialer.Dial("tcp", address)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Close closes the supervisor before the connection
+	if c.supervisor.State() == StateClosed {
+		conn.Close()
+		return ErrSupervisorClosed
+	}
 	c.conn = conn
+	c.lastReceived = time.Now()
+
+	// Each connection gets to prove again that it supports the window
+	c.windowFallback = false
+	c.resizeWindow()
+
+	go c.readLoop(conn)
 	return nil
 }
 
 // Close closes the TCP connection
 func (c *TCPClient) Close() error {
+	// A dial in progress then drops its connection
+	c.supervisor.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.probeStop != nil {
+		close(c.probeStop)
+		c.probeStop = nil
+	}
+
 	if c.conn != nil {
-		return c.conn.Close()
+		err := c.conn.Close()
+		c.conn = nil
+		c.failPending(ErrConnectionClosed)
+		return err
 	}
 	return nil
 }
 
-// SetTimeout sets the communication timeout
-func (c *TCPClient) SetTimeout(timeout time.Duration) {
-	c.timeout = timeout
+// SetKeepAlive sets the TCP keepalive period used by new connections.
+// Zero uses the system default, a negative period disables keepalive.
+func (c *TCPClient) SetKeepAlive(period time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keepAlive = period
 }
 
-// sendRequest sends a Modbus TCP request
-func (c *TCPClient) sendRequest(slaveID byte, pdu *PDU) ([]byte, error) {
-	if c.conn == nil {
-		return nil, fmt.Errorf("not connected")
+// SetProbeInterval enables periodic supervision of the connection. Once
+// nothing has been received for interval a probe request is sent with
+// the timeout as deadline; a connection that does not answer is closed,
+// even if TCP still sees it open, and redialed by the supervisor before
+// the next request runs into it. The probe is a real request that
+// gateways forward to their serial bus, see SetProbeRequest. TCP
+// keepalive notices silent peers without bus traffic. Zero disables
+// probing. It takes effect on the next Connect.
+func (c *TCPClient) SetProbeInterval(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probeInterval = interval
+}
+
+// SetProbeRequest sets the request sent by the probe, a read of holding
+// register 0 of unit 0xFF by default. Any response, exceptions included,
+// proves the connection works. Behind an RTU gateway, a unit without
+// device costs a serial timeout on every probe; probe a device that
+// answers instead.
+func (c *TCPClient) SetProbeRequest(slaveID byte, pdu *PDU) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probeUnitID = slaveID
+	c.probePDU = pdu
+}
+
+// OnError sets a function called with the errors of the background
+// goroutines reading the connection and redialing it, nil discards them
+func (c *TCPClient) OnError(fn func(error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onError = fn
+}
+
+// reportError passes err to the OnError function, the caller must not
+// hold c.mu
+func (c *TCPClient) reportError(err error) {
+	c.mu.Lock()
+	fn := c.onError
+	c.mu.Unlock()
+
+	if fn != nil {
+		fn(err)
 	}
+}
 
-	// Generate transaction ID
-	transID := uint16(atomic.AddUint32(&c.transactionID, 1))
+// probe checks the connection once it has been idle for interval until
+// stop is closed, closing it if the probe request fails and redialing it
+func (c *TCPClient) probe(interval time.Duration, stop <-chan struct{}) {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
 
-	// Build MBAP header
-	mbap := make([]byte, 7)
-	binary.BigEndian.PutUint16(mbap[0:2], transID)                 // Transaction ID
-	binary.BigEndian.PutUint16(mbap[2:4], 0)                       // Protocol ID
-	binary.BigEndian.PutUint16(mbap[4:6], uint16(2+len(pdu.Data))) // Length
-	mbap[6] = slaveID                                              // Unit ID
+		c.mu.Lock()
+		conn := c.conn
+		idle := time.Since(c.lastReceived)
+		c.mu.Unlock()
 
-	// Build complete request
-	request := append(mbap, pdu.FunctionCode)
-	request = append(request, pdu.Data...)
+		// Responses to requests prove the connection works
+		if conn != nil && idle < interval {
+			timer.Reset(interval - idle)
+			continue
+		}
+		timer.Reset(interval)
+
+		if conn != nil {
+			err := c.probeConn()
+			if err != nil {
+				c.reportError(fmt.Errorf("probe failed: %w", err))
+
+				c.mu.Lock()
+				if c.conn == conn {
+					c.dropConn(err)
+				}
+				c.unlock()
+			}
+		}
 
-	// Set write timeout
-	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
-	_, err := c.conn.Write(request)
-	if err != nil {
-		return nil, fmt.Errorf("write failed: %w", err)
+		c.mu.Lock()
+		select {
+		case <-stop:
+			c.mu.Unlock()
+			return
+		default:
+		}
+		redial := c.conn == nil
+		c.mu.Unlock()
+
+		var err error
+		if redial {
+			err = c.connect()
+		}
+
+		// Waiting out the backoff, or for a request redialing or Close,
+		// is not a failure
+		if err != nil && !errors.Is(err, ErrReconnectBackoff) &&
+			!errors.Is(err, ErrConnectInProgress) && !errors.Is(err, ErrSupervisorClosed) {
+			c.reportError(err)
+		}
+		if redial && err == nil {
+			c.reconnected()
+		}
 	}
+}
 
-	// Read response
-	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
-	header := make([]byte, 7)
-	_, err = c.conn.Read(header)
-	if err != nil {
-		return nil, fmt.Errorf("read header failed: %w", err)
+// probeConn sends the probe request, waiting for its response until the
+// timeout
+func (c *TCPClient) probeConn() error {
+	c.mu.Lock()
+	unitID := c.probeUnitID
+	pdu := c.probePDU
+	timeout := c.timeout
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err := c.exchange(ctx, unitID, pdu)
+
+	// An exception response proves the connection works
+	var modbusErr *ModbusError
+	if errors.As(err, &modbusErr) {
+		return nil
 	}
+	return err
+}
 
-	// Parse MBAP header
-	respTransID := binary.BigEndian.Uint16(header[0:2])
-	length := binary.BigEndian.Uint16(header[4:6])
+// SetAutoReconnect makes requests failing on a closed or reset connection
+// redial and retry transparently, up to retries redials per request.
+// Failed dials wait for backoff, the supervisor backoff if nil. Zero
+// retries disables it.
+func (c *TCPClient) SetAutoReconnect(retries int, backoff Backoff) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if respTransID != transID {
-		return nil, ErrInvalidResponse
+	c.reconnectRetries = max(retries, 0)
+	if backoff != nil {
+		c.supervisor.SetBackoff(backoff)
 	}
+}
 
-	// Read PDU
-	pduData := make([]byte, length-1) // -1 for unit ID already read
-	_, err = c.conn.Read(pduData)
-	if err != nil {
-		return nil, fmt.Errorf("read PDU failed: %w", err)
+// OnReconnect sets a function called after a broken connection was
+// redialed by a request or the prober
+func (c *TCPClient) OnReconnect(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onReconnect = fn
+}
+
+// reconnected calls the OnReconnect function, the caller must not hold c.mu
+func (c *TCPClient) reconnected() {
+	c.mu.Lock()
+	fn := c.onReconnect
+	c.mu.Unlock()
+
+	if fn != nil {
+		fn()
 	}
+}
 
-	// Check for exception
-	if pduData[0] == (pdu.FunctionCode | 0x80) {
-		if len(pduData) >= 2 {
-			return nil, &ModbusError{
-				FunctionCode:  pdu.FunctionCode,
-				ExceptionCode: pduData[1],
-			}
+// redial opens a new connection unless another request already did,
+// waiting for the backoff after failed dials
+func (c *TCPClient) redial(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		connected := c.conn != nil
+		c.mu.Unlock()
+		if connected {
+			return nil
+		}
+
+		err := c.connect()
+		if err == nil {
+			c.reconnected()
+			return nil
+		}
+
+		var wait time.Duration
+		switch {
+		case errors.Is(err, ErrReconnectBackoff):
+			wait = time.Until(c.supervisor.Stats().NextAttempt)
+		case errors.Is(err, ErrConnectInProgress):
+			// Another request or the prober is dialing
+			wait = 10 * time.Millisecond
+		default:
+			return err
+		}
+		err = sleepContext(ctx, max(wait, time.Millisecond))
+		if err != nil {
+			return err
 		}
-		return nil, ErrInvalidResponse
 	}
+}
 
-	return pduData[1:], nil // Return data without function code
+// isConnectionError reports whether err means the connection is gone
+func isConnectionError(err error) bool {
+	return errors.Is(err, ErrNotConnected) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNABORTED)
 }
 
-// ReadCoils reads coil status
-func (c *TCPClient) ReadCoils(slaveID byte, address uint16, quantity uint16) ([]bool, error) {
-	if quantity == 0 || quantity > 2000 {
-		return nil, ErrInvalidQuantity
-	}
+// SetTimeout sets the communication timeout
+func (c *TCPClient) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+}
 
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], quantity)
+// SetMatchingWindow sets how many transactions may be outstanding at once
+// on the connection. Responses are matched to their request by transaction
+// ID, so gateways may answer them in any order. Defaults to 1, which most
+// devices require. A connection that answers with unknown transaction IDs
+// or mismatched function codes falls back to 1 until it is redialed.
+func (c *TCPClient) SetMatchingWindow(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.window = max(n, 1)
+	c.resizeWindow()
+}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeReadCoils,
-		Data:         data,
-	}
+// MatchingWindow returns how many transactions may currently be
+// outstanding, 1 after a fallback
+func (c *TCPClient) MatchingWindow() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return cap(c.slots)
+}
 
-	response, err := c.sendRequest(slaveID, pdu)
-	if err != nil {
-		return nil, err
+// mismatch records a response that does not match its request, the
+// caller must hold c.mu
+func (c *TCPClient) mismatch() {
+	if cap(c.slots) > 1 {
+		c.windowFallback = true
+		c.resizeWindow()
 	}
+}
 
-	if len(response) < 1 {
-		return nil, ErrInvalidResponse
+// resizeWindow applies the matching window, the caller must hold c.mu.
+// Requests in progress release their slot to the previous window.
+func (c *TCPClient) resizeWindow() {
+	window := c.window
+	if c.transIDStrategy == TransactionIDConstant || c.windowFallback {
+		// Responses cannot be told apart
+		window = 1
 	}
+	if window != cap(c.slots) {
+		c.slots = make(chan struct{}, window)
+	}
+}
+
+// SetProtocolID sets the MBAP protocol ID sent with each request.
+// The Modbus specification uses 0, some vendor extensions use other values.
+func (c *TCPClient) SetProtocolID(protocolID uint16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.protocolID = protocolID
+}
 
-	return bytesToBools(response[1:], quantity), nil
+// SetProtocolIDPolicy sets how the protocol ID of responses is validated
+func (c *TCPClient) SetProtocolIDPolicy(policy ProtocolIDPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.protocolIDPolicy = policy
 }
 
-// ReadDiscreteInputs reads discrete input status
-func (c *TCPClient) ReadDiscreteInputs(slaveID byte, address uint16, quantity uint16) ([]bool, error) {
-	if quantity == 0 || quantity > 2000 {
-		return nil, ErrInvalidQuantity
+// SetLenientUnitID disables validation of the unit ID in responses.
+// Use it for gateways that rewrite the unit ID.
+func (c *TCPClient) SetLenientUnitID(lenient bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lenientUnitID = lenient
+}
+
+// SetWriteOnly marks a device as reachable over a link that never returns
+// responses. Writes to it return as soon as they are sent and reads fail
+// with ErrWriteOnly.
+func (c *TCPClient) SetWriteOnly(slaveID byte, writeOnly bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writeOnly == nil {
+		c.writeOnly = make(map[byte]bool)
 	}
+	c.writeOnly[slaveID] = writeOnly
+}
+
+// SetTransactionIDStrategy sets how transaction IDs are generated
+func (c *TCPClient) SetTransactionIDStrategy(strategy TransactionIDStrategy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.transIDStrategy = strategy
+	c.resizeWindow()
+}
 
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], quantity)
+// SetTransactionID sets the current transaction ID: the value used by the
+// constant strategy, or the one the next increment starts from
+func (c *TCPClient) SetTransactionID(transactionID uint16) {
+	atomic.StoreUint32(&c.transactionID, uint32(transactionID))
+}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeReadDiscreteInputs,
-		Data:         data,
+// TransactionID returns the transaction ID of the last request
+func (c *TCPClient) TransactionID() uint16 {
+	return uint16(atomic.LoadUint32(&c.transactionID))
+}
+
+// nextTransactionID generates a transaction ID, the caller must hold c.mu.
+// IDs of waiting requests and of unexpired abandoned ones are skipped,
+// except by the constant strategy.
+func (c *TCPClient) nextTransactionID() uint16 {
+	c.expireAbandoned(time.Now())
+
+	switch c.transIDStrategy {
+	case TransactionIDRandom:
+		transID := uint16(rand.Uint32())
+		for c.transactionIDInUse(transID) {
+			transID = uint16(rand.Uint32())
+		}
+		atomic.StoreUint32(&c.transactionID, uint32(transID))
+		return transID
+	case TransactionIDConstant:
+		return uint16(atomic.LoadUint32(&c.transactionID))
 	}
 
-	response, err := c.sendRequest(slaveID, pdu)
-	if err != nil {
-		return nil, err
+	transID := uint16(atomic.AddUint32(&c.transactionID, 1))
+	for i := 0; i < 0xFFFF && c.transactionIDInUse(transID); i++ {
+		transID = uint16(atomic.AddUint32(&c.transactionID, 1))
 	}
+	return transID
+}
 
-	if len(response) < 1 {
-		return nil, ErrInvalidResponse
+// transactionIDInUse tells whether a response with transID may still
+// arrive, the caller must hold c.mu
+func (c *TCPClient) transactionIDInUse(transID uint16) bool {
+	_, pending := c.pending[transID]
+	_, abandoned := c.abandoned[transID]
+	return pending || abandoned || c.custom[transID]
+}
+
+// SetCapture sends a copy of every frame sent and received to capture,
+// nil stops capturing
+func (c *TCPClient) SetCapture(capture *FrameCapture) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capture = capture
+}
+
+// WriteFrame sends pdu to slaveID in an MBAP frame and returns the
+// transaction ID of the frame. Together with ReadFrame it allows custom
+// request interleaving alongside regular requests.
+func (c *TCPClient) WriteFrame(slaveID byte, pdu *PDU) (uint16, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	transID := c.nextTransactionID()
+	err := c.writeFrame(transID, slaveID, pdu)
+	if err != nil {
+		return 0, err
 	}
+	c.custom[transID] = true
+	return transID, nil
+}
 
-	return bytesToBools(response[1:], quantity), nil
+// ReadFrame returns the next received MBAP frame answering a frame sent
+// with WriteFrame, with its transaction ID. Frames answering neither
+// those nor regular requests are dropped. The protocol ID is validated
+// according to the policy.
+func (c *TCPClient) ReadFrame() (uint16, *ADU, error) {
+	c.mu.Lock()
+	timeout := c.timeout
+	strict := c.protocolIDPolicy == ProtocolIDStrict
+	protocolID := c.protocolID
+	c.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case frame := <-c.unclaimed:
+		if strict && frame.protoID != protocolID {
+			return 0, nil, ErrInvalidProtocolID
+		}
+		return frame.transID, frame.adu, nil
+	case <-timer.C:
+		return 0, nil, ErrTimeout
+	}
 }
 
-// ReadHoldingRegisters reads holding registers
-func (c *TCPClient) ReadHoldingRegisters(slaveID byte, address uint16, quantity uint16) ([]uint16, error) {
-	if quantity == 0 || quantity > 125 {
-		return nil, ErrInvalidQuantity
+// writeFrame sends an MBAP frame, the caller must hold c.mu
+func (c *TCPClient) writeFrame(transID uint16, slaveID byte, pdu *PDU) error {
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+
+	// Set write timeout
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	err := c.writer.writeTo(c.conn, transID, c.protocolID, slaveID, pdu)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
 	}
 
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], quantity)
+	c.capture.capture(FrameSent, c.writer.header[:], pdu.Data)
+	return nil
+}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeReadHoldingRegisters,
-		Data:         data,
+// readLoop reads the frames received on conn and hands them to the
+// waiting requests, until the connection fails or is closed
+func (c *TCPClient) readLoop(conn net.Conn) {
+	for {
+		header, pduData, err := readMBAPFrame(conn)
+		if err != nil {
+			c.mu.Lock()
+			// Otherwise the connection was closed on purpose
+			failed := c.conn == conn
+			if failed {
+				c.dropConn(err)
+			}
+			c.unlock()
+
+			if failed {
+				c.reportError(err)
+			}
+			return
+		}
+
+		c.dispatch(header, pduData, time.Now())
 	}
+}
 
-	response, err := c.sendRequest(slaveID, pdu)
+// readMBAPFrame reads an MBAP header and the PDU following it. Frames
+// may arrive split across segments or several in one segment, the length
+// field tells where each ends.
+func readMBAPFrame(conn net.Conn) ([]byte, []byte, error) {
+	header := make([]byte, 7)
+	_, err := io.ReadFull(conn, header)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("read header failed: %w", err)
 	}
 
-	if len(response) < 1 {
-		return nil, ErrInvalidResponse
+	// A length out of range leaves no way to find the next frame
+	length := binary.BigEndian.Uint16(header[4:6])
+	if length < 2 || length > 254 {
+		return nil, nil, fmt.Errorf("%w: MBAP length %d", ErrInvalidResponse, length)
 	}
 
-	return bytesToUint16s(response[1:]), nil
+	// Read PDU
+	pduData := make([]byte, length-1) // -1 for unit ID already read
+	_, err = io.ReadFull(conn, pduData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read PDU failed: %w", err)
+	}
+	return header, pduData, nil
 }
 
-// ReadInputRegisters reads input registers
-func (c *TCPClient) ReadInputRegisters(slaveID byte, address uint16, quantity uint16) ([]uint16, error) {
-	if quantity == 0 || quantity > 125 {
-		return nil, ErrInvalidQuantity
+// dispatch hands a received frame to the request waiting for it
+func (c *TCPClient) dispatch(header []byte, pduData []byte, received time.Time) {
+	// Parse MBAP header
+	frame := tcpFrame{
+		transID: binary.BigEndian.Uint16(header[0:2]),
+		protoID: binary.BigEndian.Uint16(header[2:4]),
+		adu: &ADU{
+			SlaveID: header[6],
+			PDU: &PDU{
+				FunctionCode: pduData[0],
+				Data:         pduData[1:],
+			},
+		},
+		received: received,
+	}
+
+	c.mu.Lock()
+	c.lastReceived = received
+	c.capture.capture(FrameReceived, header, pduData)
+	result, ok := c.pending[frame.transID]
+	delete(c.pending, frame.transID)
+	_, late := c.abandoned[frame.transID]
+	delete(c.abandoned, frame.transID)
+	custom := c.custom[frame.transID]
+	delete(c.custom, frame.transID)
+	stray := !ok && !late && !custom
+	if stray {
+		c.mismatch()
+	}
+	c.mu.Unlock()
+
+	switch {
+	case ok:
+		result <- tcpResult{frame: frame}
+	case custom:
+		// Response to a frame sent with WriteFrame, kept for ReadFrame
+		select {
+		case c.unclaimed <- frame:
+		default:
+			c.reportError(fmt.Errorf("%w: unclaimed frame with transaction ID %d dropped",
+				ErrInvalidResponse, frame.transID))
+		}
+	case stray:
+		// A stray or duplicate response must not reach ReadFrame
+		c.reportError(fmt.Errorf("%w: unexpected frame with transaction ID %d dropped",
+			ErrInvalidResponse, frame.transID))
 	}
+	// Otherwise a late response to a request that timed out
+}
+
+// dropConn closes a broken connection and fails the requests waiting on
+// it, the caller must hold c.mu and release it with unlock
+func (c *TCPClient) dropConn(err error) {
+	if c.conn == nil {
+		return
+	}
+	c.conn.Close()
+	c.conn = nil
+	c.failPending(err)
+	if event, ok := c.supervisor.fail(err); ok {
+		c.events = append(c.events, event)
+	}
+}
+
+// unlock releases c.mu, then emits the supervisor events queued meanwhile
+// so that callbacks may call the client
+func (c *TCPClient) unlock() {
+	events := c.events
+	c.events = nil
+	c.mu.Unlock()
 
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], quantity)
+	for _, event := range events {
+		c.supervisor.emit(event)
+	}
+}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeReadInputRegisters,
-		Data:         data,
+// failPending fails every waiting request, the caller must hold c.mu
+func (c *TCPClient) failPending(err error) {
+	for transID, result := range c.pending {
+		result <- tcpResult{err: err}
+		delete(c.pending, transID)
 	}
+	clear(c.abandoned)
+	clear(c.custom)
+}
 
-	response, err := c.sendRequest(slaveID, pdu)
-	if err != nil {
-		return nil, err
+// lateResponseTTL is how long a late response to an abandoned transaction
+// is expected, its ID is not reused meanwhile
+const lateResponseTTL = time.Minute
+
+// abandon stops waiting for the response to a transaction, leaving a
+// marker so a late response is dropped
+func (c *TCPClient) abandon(transID uint16, result chan tcpResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending[transID] == result {
+		delete(c.pending, transID)
+		c.abandoned[transID] = time.Now().Add(lateResponseTTL)
 	}
+}
 
-	if len(response) < 1 {
-		return nil, ErrInvalidResponse
+// expireAbandoned removes the markers of abandoned transactions whose
+// response never arrived, the caller must hold c.mu
+func (c *TCPClient) expireAbandoned(now time.Time) {
+	for transID, expiry := range c.abandoned {
+		if now.After(expiry) {
+			delete(c.abandoned, transID)
+		}
 	}
+}
 
-	return bytesToUint16s(response[1:]), nil
+// Endpoint returns the address of the server
+func (c *TCPClient) Endpoint() string {
+	return c.address
 }
 
-// WriteSingleCoil writes a single coil
-func (c *TCPClient) WriteSingleCoil(slaveID byte, address uint16, value bool) error {
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	if value {
-		binary.BigEndian.PutUint16(data[2:4], 0xFF00)
-	} else {
-		binary.BigEndian.PutUint16(data[2:4], 0x0000)
+// Send sends a request once, redialing a broken connection if enabled.
+// It implements Transporter, requests are usually sent through the
+// function code methods.
+func (c *TCPClient) Send(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	c.mu.Lock()
+	retries := c.reconnectRetries
+	c.mu.Unlock()
+
+	response, err := c.exchange(ctx, slaveID, pdu)
+	for attempt := 0; attempt < retries && isConnectionError(err); attempt++ {
+		err = c.redial(ctx)
+		if err == nil {
+			response, err = c.exchange(ctx, slaveID, pdu)
+		} else if ctx.Err() == nil && !errors.Is(err, ErrSupervisorClosed) {
+			// Keep redialing until the retries run out
+			err = fmt.Errorf("%w: %w", ErrNotConnected, err)
+		}
 	}
+	return response, err
+}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeWriteSingleCoil,
-		Data:         data,
+// exchange sends a Modbus TCP request, waiting for the response until
+// the timeout or until ctx is done
+func (c *TCPClient) exchange(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	_, err := c.sendRequest(slaveID, pdu)
-	return err
-}
+	c.mu.Lock()
+	slots := c.slots
+	timeout := c.timeout
+	c.mu.Unlock()
 
-// WriteSingleRegister writes a single register
-func (c *TCPClient) WriteSingleRegister(slaveID byte, address uint16, value uint16) error {
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], value)
+	// Wait for a free slot in the matching window
+	slotTimer := time.NewTimer(timeout)
+	defer slotTimer.Stop()
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	case <-slotTimer.C:
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeWriteSingleRegister,
-		Data:         data,
+	c.mu.Lock()
+	if c.conn == nil {
+		c.mu.Unlock()
+		return nil, ErrNotConnected
 	}
 
-	_, err := c.sendRequest(slaveID, pdu)
-	return err
-}
+	writeOnly := c.writeOnly[slaveID]
+	if writeOnly && !isWriteFunction(pdu.FunctionCode) {
+		c.mu.Unlock()
+		return nil, ErrWriteOnly
+	}
 
-// WriteMultipleCoils writes multiple coils
-func (c *TCPClient) WriteMultipleCoils(slaveID byte, address uint16, values []bool) error {
-	if len(values) == 0 || len(values) > 1968 {
-		return ErrInvalidQuantity
+	// Register before writing, the response may arrive at once
+	transID := c.nextTransactionID()
+	result := make(chan tcpResult, 1)
+	if !writeOnly {
+		c.pending[transID] = result
+		delete(c.abandoned, transID)
 	}
 
-	byteCount := (len(values) + 7) / 8
-	data := make([]byte, 5+byteCount)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], uint16(len(values)))
-	data[4] = byte(byteCount)
+	strict := c.protocolIDPolicy == ProtocolIDStrict
+	protocolID := c.protocolID
+	lenient := c.lenientUnitID
 
-	coilBytes := boolsToBytes(values)
-	copy(data[5:], coilBytes)
+	meta := readMetaFrom(ctx)
+	meta.sent(transID)
+	err := c.writeFrame(transID, slaveID, pdu)
+	if err != nil {
+		delete(c.pending, transID)
+		c.dropConn(err)
+		c.unlock()
+		return nil, err
+	}
+	c.mu.Unlock()
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeWriteMultipleCoils,
-		Data:         data,
+	if writeOnly {
+		return nil, nil
 	}
 
-	_, err := c.sendRequest(slaveID, pdu)
-	return err
-}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
 
-// WriteMultipleRegisters writes multiple registers
-func (c *TCPClient) WriteMultipleRegisters(slaveID byte, address uint16, values []uint16) error {
-	if len(values) == 0 || len(values) > 123 {
-		return ErrInvalidQuantity
+	var frame tcpFrame
+	select {
+	case res := <-result:
+		if res.err != nil {
+			return nil, res.err
+		}
+		frame = res.frame
+		meta.received(frame.received)
+	case <-timer.C:
+		c.abandon(transID, result)
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		c.abandon(transID, result)
+		return nil, ctx.Err()
 	}
 
-	data := make([]byte, 5+len(values)*2)
-	binary.BigEndian.PutUint16(data[0:2], address)
-	binary.BigEndian.PutUint16(data[2:4], uint16(len(values)))
-	data[4] = byte(len(values) * 2)
+	if strict && frame.protoID != protocolID {
+		return nil, ErrInvalidProtocolID
+	}
 
-	regBytes := uint16sToBytes(values)
-	copy(data[5:], regBytes)
+	adu := frame.adu
+	if adu.PDU.FunctionCode&0x7F != pdu.FunctionCode {
+		c.mu.Lock()
+		c.mismatch()
+		c.mu.Unlock()
+		return nil, ErrInvalidResponse
+	}
 
-	pdu := &PDU{
-		FunctionCode: FuncCodeWriteMultipleRegisters,
-		Data:         data,
+	if !lenient && adu.SlaveID != slaveID {
+		return nil, fmt.Errorf("%w: expected unit ID %d, got %d", ErrInvalidSlaveID, slaveID, adu.SlaveID)
 	}
 
-	_, err := c.sendRequest(slaveID, pdu)
-	return err
+	// Check for exception
+	if adu.PDU.FunctionCode == (pdu.FunctionCode | 0x80) {
+		if len(adu.PDU.Data) >= 1 {
+			return nil, &ModbusError{
+				FunctionCode:  pdu.FunctionCode,
+				ExceptionCode: adu.PDU.Data[0],
+			}
+		}
+		return nil, ErrInvalidResponse
+	}
+
+	return adu.PDU.Data, nil // Return data without function code
 }
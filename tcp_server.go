@@ -0,0 +1,234 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// TCPServer implements Modbus TCP server serving a DataStore
+type TCPServer struct {
+	address     string
+	store       DataStore
+	idleTimeout time.Duration
+	onError     func(error)
+	functions   FunctionFilter
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewTCPServer creates a new Modbus TCP server listening on address
+func NewTCPServer(address string, store DataStore) *TCPServer {
+	return &TCPServer{
+		address: address,
+		store:   store,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// SetIdleTimeout closes connections without requests for timeout,
+// zero keeps them open
+func (s *TCPServer) SetIdleTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idleTimeout = timeout
+}
+
+// OnError sets a function called with the errors ending a connection,
+// such as malformed frames or failed writes, nil discards them. Clients
+// closing the connection, idle timeouts and Close are not reported.
+func (s *TCPServer) OnError(fn func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onError = fn
+}
+
+// reportError passes a connection error to the OnError function
+func (s *TCPServer) reportError(conn net.Conn, err error) {
+	s.mu.Lock()
+	fn := s.onError
+	closed := s.closed
+	s.mu.Unlock()
+
+	var netErr net.Error
+	if fn == nil || closed || errors.Is(err, io.EOF) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return
+	}
+	fn(fmt.Errorf("connection %s: %w", conn.RemoteAddr(), err))
+}
+
+// Functions returns the filter enabling the function codes served
+func (s *TCPServer) Functions() *FunctionFilter {
+	return &s.functions
+}
+
+// Listen opens the listening socket
+func (s *TCPServer) Listen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrServerClosed
+	}
+
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	s.listener = listener
+	return nil
+}
+
+// Addr returns the address the server listens on, nil before Listen
+func (s *TCPServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Serve accepts connections until Close is called, it returns
+// ErrServerClosed after Close
+func (s *TCPServer) Serve() error {
+	s.mu.Lock()
+	listener := s.listener
+	s.mu.Unlock()
+
+	if listener == nil {
+		return fmt.Errorf("not listening")
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return fmt.Errorf("accept failed: %w", err)
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// ListenAndServe opens the listening socket and serves connections
+// until Close is called
+func (s *TCPServer) ListenAndServe() error {
+	err := s.Listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Close stops listening, closes all connections and waits for them to end
+func (s *TCPServer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// serveConn answers requests on a connection until it is closed
+func (s *TCPServer) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	header := make([]byte, 7)
+	for {
+		s.mu.Lock()
+		idleTimeout := s.idleTimeout
+		s.mu.Unlock()
+
+		if idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		} else {
+			conn.SetReadDeadline(time.Time{})
+		}
+
+		_, err := io.ReadFull(conn, header)
+		if err != nil {
+			s.reportError(conn, err)
+			return
+		}
+
+		// Parse MBAP header
+		protoID := binary.BigEndian.Uint16(header[2:4])
+		length := binary.BigEndian.Uint16(header[4:6])
+		unitID := header[6]
+
+		// A frame that is not Modbus cannot be skipped reliably
+		if protoID != 0 || length < 2 || length > 254 {
+			s.reportError(conn, fmt.Errorf("%w: protocol ID %d, length %d", ErrInvalidRequest, protoID, length))
+			return
+		}
+
+		request := make([]byte, length-1) // -1 for unit ID already read
+		_, err = io.ReadFull(conn, request)
+		if err != nil {
+			s.reportError(conn, err)
+			return
+		}
+
+		response := handleRequest(s.store, &s.functions, unitID, &PDU{
+			FunctionCode: request[0],
+			Data:         request[1:],
+		})
+
+		frame := make([]byte, 8, 8+len(response.Data))
+		copy(frame, header)
+		binary.BigEndian.PutUint16(frame[4:6], uint16(2+len(response.Data)))
+		frame[7] = response.FunctionCode
+		frame = append(frame, response.Data...)
+
+		_, err = conn.Write(frame)
+		if err != nil {
+			s.reportError(conn, fmt.Errorf("write failed: %w", err))
+			return
+		}
+	}
+}
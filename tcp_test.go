@@ -0,0 +1,330 @@
+package modbus
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A connection that stays open but never answers is closed by the probe
+// and redialed
+func TestTCPClientProbeRedialsHalfOpenConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	c := NewTCPClient(listener.Addr().String())
+	c.SetTimeout(100 * time.Millisecond)
+	c.SetProbeInterval(50 * time.Millisecond)
+	reconnected := make(chan struct{}, 1)
+	c.OnReconnect(func() {
+		select {
+		case reconnected <- struct{}{}:
+		default:
+		}
+	})
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The black hole: accepted, read by nobody, never closed
+	first := <-accepted
+	defer first.Close()
+
+	select {
+	case conn := <-accepted:
+		conn.Close()
+	case <-time.After(2 * time.Second):
+		t.Fatal("half-open connection not redialed")
+	}
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second):
+		t.Fatal("OnReconnect not called")
+	}
+}
+
+// The probe only sends requests on a connection that has been idle for
+// the interval
+func TestTCPClientProbeOnlyIdleConnection(t *testing.T) {
+	var probes atomic.Int32
+	address := serveMBAP(t, func(conn net.Conn, requests <-chan []byte) {
+		for request := range requests {
+			if request[6] == 0xFF {
+				probes.Add(1)
+			}
+			conn.Write(registerResponse(request))
+		}
+	})
+
+	c := NewTCPClient(address)
+	c.SetProbeInterval(100 * time.Millisecond)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for range 20 {
+		if _, err := c.ReadHoldingRegisters(1, 0, 1); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if n := probes.Load(); n != 0 {
+		t.Errorf("%d probes sent on a busy connection", n)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if probes.Load() == 0 {
+		t.Error("no probe sent on an idle connection")
+	}
+}
+
+// Settings may change while requests are in flight
+func TestTCPClientSettersDuringRequests(t *testing.T) {
+	s := startTCPServer(t)
+
+	c := NewTCPClient(s.Addr().String())
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 100 {
+			c.SetProtocolIDPolicy(ProtocolIDIgnore)
+			c.SetProtocolID(0)
+			c.SetLenientUnitID(i%2 == 0)
+			c.SetKeepAlive(time.Minute)
+			c.SetProbeInterval(0)
+		}
+	}()
+
+	for range 20 {
+		if _, err := c.ReadHoldingRegisters(1, 0, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+// serveMBAP accepts one connection and hands every request frame to
+// respond, which owns the connection and writes the responses
+func serveMBAP(t *testing.T, respond func(conn net.Conn, requests <-chan []byte)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		requests := make(chan []byte)
+		go func() {
+			defer close(requests)
+			for {
+				header, pduData, err := readMBAPFrame(conn)
+				if err != nil {
+					return
+				}
+				requests <- append(header, pduData...)
+			}
+		}()
+		respond(conn, requests)
+	}()
+	return listener.Addr().String()
+}
+
+// registerResponse answers a read holding registers request of one
+// register with the register address as value
+func registerResponse(request []byte) []byte {
+	return []byte{
+		request[0], request[1], 0, 0, 0, 5, request[6],
+		FuncCodeReadHoldingRegisters, 2, request[8], request[9],
+	}
+}
+
+// Responses are matched by transaction ID, whatever their order
+func TestTCPClientOutOfOrderResponses(t *testing.T) {
+	address := serveMBAP(t, func(conn net.Conn, requests <-chan []byte) {
+		first, second := <-requests, <-requests
+		conn.Write(registerResponse(second))
+		conn.Write(registerResponse(first))
+	})
+
+	c := NewTCPClient(address)
+	c.SetMatchingWindow(2)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for _, register := range []uint16{1, 2} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values, err := c.ReadHoldingRegisters(1, register, 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if values[0] != register {
+				t.Errorf("register %d: got %d", register, values[0])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// A late response to a request that timed out is dropped, and its
+// transaction ID is not reused while the response may still arrive
+func TestTCPClientLateResponse(t *testing.T) {
+	address := serveMBAP(t, func(conn net.Conn, requests <-chan []byte) {
+		timedOut, next := <-requests, <-requests
+		conn.Write(registerResponse(timedOut))
+		conn.Write(registerResponse(next))
+	})
+
+	c := NewTCPClient(address)
+	c.SetTimeout(100 * time.Millisecond)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_, err := c.ReadHoldingRegisters(1, 1, 1)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want ErrTimeout", err)
+	}
+
+	// The next increment would reuse the ID of the timed out request
+	c.SetTransactionID(c.TransactionID() - 1)
+	values, err := c.ReadHoldingRegisters(1, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0] != 2 {
+		t.Errorf("got the late response %d", values[0])
+	}
+}
+
+// Supervisor callbacks may call the client
+func TestTCPClientEventCallbacksCallClient(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	c := NewTCPClient(listener.Addr().String())
+	events := make(chan SupervisorEvent, 16)
+	c.Supervisor().OnEvent(func(event SupervisorEvent) {
+		c.SetTimeout(time.Second)
+		events <- event
+	})
+
+	wait := func(want SupervisorState) {
+		t.Helper()
+		for {
+			select {
+			case event := <-events:
+				if event.To == want {
+					return
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("no transition to %v", want)
+			}
+		}
+	}
+
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	wait(StateConnected)
+
+	// The read loop reports the broken connection
+	(<-accepted).Close()
+	wait(StateDisconnected)
+
+	c.Close()
+	wait(StateClosed)
+}
+
+// A stray frame is dropped instead of being returned by ReadFrame
+func TestTCPClientReadFrameDropsStrayFrames(t *testing.T) {
+	address := serveMBAP(t, func(conn net.Conn, requests <-chan []byte) {
+		request := <-requests
+		stray := registerResponse(request)
+		stray[0], stray[1] = 0x77, 0x77
+		conn.Write(stray)
+		conn.Write(registerResponse(request))
+		<-requests
+	})
+
+	c := NewTCPClient(address)
+	c.SetTimeout(200 * time.Millisecond)
+	reported := make(chan error, 4)
+	c.OnError(func(err error) { reported <- err })
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	pdu := &PDU{FunctionCode: FuncCodeReadHoldingRegisters, Data: []byte{0, 5, 0, 1}}
+	transID, err := c.WriteFrame(1, pdu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotID, adu, err := c.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotID != transID || adu.PDU.Data[2] != 5 {
+		t.Errorf("ReadFrame() = %d, % X, want transaction %d", gotID, adu.PDU.Data, transID)
+	}
+
+	select {
+	case err := <-reported:
+		if !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("reported %v, want %v", err, ErrInvalidResponse)
+		}
+	case <-time.After(time.Second):
+		t.Error("stray frame not reported")
+	}
+}
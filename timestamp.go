@@ -0,0 +1,145 @@
+package modbus
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampFormat is the register layout of a device timestamp
+type TimestampFormat int
+
+const (
+	// EpochSeconds is a 32-bit count of seconds since 1970-01-01 in 2 registers
+	EpochSeconds TimestampFormat = iota
+	// BCDDateTime is YY MM, DD hh, mm ss in 3 registers, one BCD byte each
+	BCDDateTime
+)
+
+// Size returns the number of registers of the timestamp
+func (f TimestampFormat) Size() int {
+	if f == BCDDateTime {
+		return 3
+	}
+	return 2
+}
+
+// DecodeEpochSeconds decodes a timestamp stored as seconds since epoch in
+// 2 registers. Devices counting from local midnight of 1970-01-01 rather
+// than UTC are handled by passing their location, nil means UTC.
+func DecodeEpochSeconds(registers []uint16, order WordOrder, loc *time.Location) (time.Time, error) {
+	if len(registers) < 2 {
+		return time.Time{}, ErrInvalidTimestamp
+	}
+
+	high, low := registers[0], registers[1]
+	if order == LowWordFirst {
+		high, low = low, high
+	}
+	seconds := int64(high)<<16 | int64(low)
+
+	t := time.Unix(seconds, 0).UTC()
+	if loc == nil {
+		return t, nil
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+}
+
+// DecodeBCDDateTime decodes a timestamp stored as BCD bytes YY MM DD hh mm ss
+// in 3 registers, in the device location, nil means UTC. Years are 2000-2099.
+func DecodeBCDDateTime(registers []uint16, loc *time.Location) (time.Time, error) {
+	if len(registers) < 3 {
+		return time.Time{}, ErrInvalidTimestamp
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var fields [6]int
+	for i := range fields {
+		b := byte(registers[i/2] >> 8)
+		if i%2 == 1 {
+			b = byte(registers[i/2])
+		}
+		value, ok := bcdToInt(b)
+		if !ok {
+			return time.Time{}, fmt.Errorf("%w: invalid BCD byte 0x%02X", ErrInvalidTimestamp, b)
+		}
+		fields[i] = value
+	}
+
+	year, month, day := 2000+fields[0], fields[1], fields[2]
+	hour, minute, second := fields[3], fields[4], fields[5]
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, fmt.Errorf("%w: %02d-%02d-%02d %02d:%02d:%02d",
+			ErrInvalidTimestamp, fields[0], month, day, hour, minute, second)
+	}
+
+	return time.Date(year, time.Month(month), day, hour, minute, second, 0, loc), nil
+}
+
+func bcdToInt(b byte) (int, bool) {
+	high, low := b>>4, b&0x0F
+	if high > 9 || low > 9 {
+		return 0, false
+	}
+	return int(high)*10 + int(low), true
+}
+
+// EventRecord is a decoded timestamped event
+type EventRecord struct {
+	Time  time.Time
+	Value float64
+}
+
+// EventRecordLayout describes event records made of a timestamp followed
+// by a single register value, as drained from device event logs
+type EventRecordLayout struct {
+	Format    TimestampFormat
+	WordOrder WordOrder      // register order of epoch seconds
+	Location  *time.Location // time zone of the device clock, UTC if nil
+	Scale     float64        // multiplier applied to the raw value, 1 if zero
+	Signed    bool           // raw value is a signed 16-bit integer
+}
+
+// Size returns the number of registers of one record
+func (l *EventRecordLayout) Size() int {
+	return l.Format.Size() + 1
+}
+
+// Decode decodes consecutive records from registers. Trailing registers
+// that do not form a complete record are ignored.
+func (l *EventRecordLayout) Decode(registers []uint16) ([]EventRecord, error) {
+	size := l.Size()
+	scale := l.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	records := make([]EventRecord, 0, len(registers)/size)
+	for offset := 0; offset+size <= len(registers); offset += size {
+		record := registers[offset : offset+size]
+
+		var t time.Time
+		var err error
+		switch l.Format {
+		case BCDDateTime:
+			t, err = DecodeBCDDateTime(record, l.Location)
+		default:
+			t, err = DecodeEpochSeconds(record, l.WordOrder, l.Location)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", offset/size, err)
+		}
+
+		raw := float64(record[size-1])
+		if l.Signed {
+			raw = float64(int16(record[size-1]))
+		}
+
+		records = append(records, EventRecord{
+			Time:  t,
+			Value: raw * scale,
+		})
+	}
+	return records, nil
+}
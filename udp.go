@@ -0,0 +1,240 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// UDPClient implements Modbus client over UDP with MBAP framing. A
+// request whose response does not arrive within the timeout is sent again
+// with the same transaction ID, up to the number of retransmissions.
+// Requests are sent one at a time.
+type UDPClient struct {
+	*GenericClient
+
+	address       string
+	conn          net.Conn
+	timeout       time.Duration
+	retransmits   int
+	transactionID uint16
+	capture       *FrameCapture
+	mu            sync.Mutex
+}
+
+// NewUDPClient creates a new Modbus UDP client retransmitting requests
+// twice
+func NewUDPClient(address string) *UDPClient {
+	c := &UDPClient{
+		address:     address,
+		timeout:     time.Second,
+		retransmits: 2,
+	}
+	c.GenericClient = NewGenericClient(c)
+	return c
+}
+
+// Connect opens the UDP socket
+func (c *UDPClient) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn, err := net.Dial("udp", c.address)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	c.conn = conn
+	return nil
+}
+
+// Close closes the UDP socket
+func (c *UDPClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil {
+		err := c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	return nil
+}
+
+// SetTimeout sets the time waited for a response before retransmitting
+func (c *UDPClient) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+}
+
+// SetRetransmits sets how many times a request without response is sent
+// again before failing with ErrTimeout, zero sends it once
+func (c *UDPClient) SetRetransmits(retransmits int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retransmits = max(retransmits, 0)
+}
+
+// SetCapture sends a copy of every frame sent and received to capture,
+// nil stops capturing
+func (c *UDPClient) SetCapture(capture *FrameCapture) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capture = capture
+}
+
+// Endpoint returns the address of the server
+func (c *UDPClient) Endpoint() string {
+	return c.address
+}
+
+// Send sends a Modbus UDP request and waits for its response,
+// retransmitting the request after every timeout, implementing
+// Transporter. The request is not sent if ctx is already done, and ctx
+// bounds the wait for the response.
+func (c *UDPClient) Send(ctx context.Context, slaveID byte, pdu *PDU) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil, ErrNotConnected
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.transactionID++
+	transID := c.transactionID
+
+	frame := make([]byte, 8, 8+len(pdu.Data))
+	binary.BigEndian.PutUint16(frame[0:2], transID)                 // Transaction ID
+	binary.BigEndian.PutUint16(frame[2:4], 0)                       // Protocol ID
+	binary.BigEndian.PutUint16(frame[4:6], uint16(2+len(pdu.Data))) // Length
+	frame[6] = slaveID                                              // Unit ID
+	frame[7] = pdu.FunctionCode
+	frame = append(frame, pdu.Data...)
+
+	// Canceling ctx interrupts the read
+	conn := c.conn
+	defer interruptRead(ctx, conn)()
+
+	meta := readMetaFrom(ctx)
+	meta.sent(transID)
+
+	var adu *ADU
+	for attempt := 0; adu == nil; attempt++ {
+		if attempt > c.retransmits {
+			return nil, ErrTimeout
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		_, err := conn.Write(frame)
+		if err != nil {
+			return nil, fmt.Errorf("write failed: %w", err)
+		}
+		c.capture.capture(FrameSent, frame)
+
+		deadline := time.Now().Add(c.timeout)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		conn.SetReadDeadline(deadline)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		adu, err = c.readResponse(transID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if adu == nil {
+			// The read deadline may be the one of ctx, or AfterFunc's on
+			// cancel: the request must not be sent again then
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if ctxDeadline, ok := ctx.Deadline(); ok && !time.Now().Before(ctxDeadline) {
+				return nil, context.DeadlineExceeded
+			}
+		}
+	}
+	meta.received(time.Now())
+
+	if adu.PDU.FunctionCode&0x7F != pdu.FunctionCode {
+		return nil, ErrInvalidResponse
+	}
+
+	if adu.SlaveID != slaveID {
+		return nil, fmt.Errorf("%w: expected unit ID %d, got %d", ErrInvalidSlaveID, slaveID, adu.SlaveID)
+	}
+
+	// Check for exception
+	if adu.PDU.FunctionCode == (pdu.FunctionCode | 0x80) {
+		if len(adu.PDU.Data) >= 1 {
+			return nil, &ModbusError{
+				FunctionCode:  pdu.FunctionCode,
+				ExceptionCode: adu.PDU.Data[0],
+			}
+		}
+		return nil, ErrInvalidResponse
+	}
+
+	return adu.PDU.Data, nil // Return data without function code
+}
+
+// interruptRead makes reads on conn return at once when ctx is done. The
+// returned stop function waits for an interruption in progress, which
+// would otherwise cut the next read short.
+func interruptRead(ctx context.Context, conn net.Conn) (stop func()) {
+	interrupted := make(chan struct{})
+	stopFunc := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+		close(interrupted)
+	})
+	return func() {
+		if !stopFunc() {
+			<-interrupted
+		}
+	}
+}
+
+// readResponse reads datagrams until the response to transID arrives,
+// returning nil at the read deadline. Late responses to earlier requests
+// and datagrams that are not Modbus are dropped.
+func (c *UDPClient) readResponse(transID uint16) (*ADU, error) {
+	buf := make([]byte, 7+254)
+	for {
+		n, err := c.conn.Read(buf)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read failed: %w", err)
+		}
+		c.capture.capture(FrameReceived, buf[:n])
+
+		if n < 8 || binary.BigEndian.Uint16(buf[0:2]) != transID ||
+			binary.BigEndian.Uint16(buf[2:4]) != 0 ||
+			int(binary.BigEndian.Uint16(buf[4:6])) != n-6 {
+			continue
+		}
+
+		return &ADU{
+			SlaveID: buf[6],
+			PDU: &PDU{
+				FunctionCode: buf[7],
+				Data:         append([]byte(nil), buf[8:n]...),
+			},
+		}, nil
+	}
+}
@@ -0,0 +1,56 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A request canceled while waiting for its response is not retransmitted
+func TestUDPClientNoRetransmitAfterCancel(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	var received atomic.Int32
+	go func() {
+		buf := make([]byte, 300)
+		for {
+			if _, _, err := server.ReadFrom(buf); err != nil {
+				return
+			}
+			received.Add(1)
+		}
+	}()
+
+	c := NewUDPClient(server.LocalAddr().String())
+	c.SetTimeout(time.Second)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = c.WriteSingleRegisterContext(ctx, 1, 0, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WriteSingleRegisterContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err = c.WriteSingleRegisterContext(ctx, 1, 0, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WriteSingleRegisterContext() = %v, want %v", err, context.Canceled)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if n := received.Load(); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+}
@@ -0,0 +1,153 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Protocol limits on the quantity of a request
+const (
+	maxReadBits           = 2000
+	maxWriteBits          = 1968
+	maxReadRegisters      = 125
+	maxWriteRegisters     = 123
+	maxReadWriteRegisters = 121 // written by FC 0x17
+)
+
+// ValidateRequest checks a request PDU against the specification: data
+// length, byte counts, quantity limits and ranges running past address
+// 0xFFFF. Servers reject requests failing it and clients can check
+// requests before sending them. Other registered function codes, vendor
+// ones included, are checked against the request length of the registry
+// when it is known. It returns ErrInvalidQuantity for malformed data and
+// quantities, ErrInvalidAddress for ranges past the address space and
+// ErrIllegalFunction for function codes that are not registered.
+func ValidateRequest(pdu *PDU) error {
+	data := pdu.Data
+
+	switch pdu.FunctionCode {
+	case FuncCodeReadCoils, FuncCodeReadDiscreteInputs:
+		if len(data) != 4 {
+			return ErrInvalidQuantity
+		}
+		return validateRange(data, maxReadBits, 4)
+
+	case FuncCodeReadHoldingRegisters, FuncCodeReadInputRegisters:
+		if len(data) != 4 {
+			return ErrInvalidQuantity
+		}
+		return validateRange(data, maxReadRegisters, 4)
+
+	case FuncCodeWriteSingleCoil:
+		if len(data) != 4 {
+			return ErrInvalidQuantity
+		}
+		value := binary.BigEndian.Uint16(data[2:4])
+		if value != 0xFF00 && value != 0x0000 {
+			return fmt.Errorf("%w: coil value 0x%04X", ErrInvalidQuantity, value)
+		}
+		return nil
+
+	case FuncCodeWriteSingleRegister:
+		if len(data) != 4 {
+			return ErrInvalidQuantity
+		}
+		return nil
+
+	case FuncCodeWriteMultipleCoils:
+		err := validateRange(data, maxWriteBits, 5)
+		if err != nil {
+			return err
+		}
+		quantity := binary.BigEndian.Uint16(data[2:4])
+		return validateByteCount(data, 4, (int(quantity)+7)/8)
+
+	case FuncCodeWriteMultipleRegisters:
+		err := validateRange(data, maxWriteRegisters, 5)
+		if err != nil {
+			return err
+		}
+		quantity := binary.BigEndian.Uint16(data[2:4])
+		return validateByteCount(data, 4, int(quantity)*2)
+
+	case FuncCodeMaskWriteRegister:
+		if len(data) != 6 {
+			return ErrInvalidQuantity
+		}
+		return nil
+
+	case FuncCodeReadWriteMultipleRegisters:
+		err := validateRange(data, maxReadRegisters, 9)
+		if err != nil {
+			return err
+		}
+		err = validateRange(data[4:], maxReadWriteRegisters, 5)
+		if err != nil {
+			return err
+		}
+		quantity := binary.BigEndian.Uint16(data[6:8])
+		return validateByteCount(data, 8, int(quantity)*2)
+
+	case FuncCodeReadFIFOQueue:
+		if len(data) != 2 {
+			return ErrInvalidQuantity
+		}
+		return nil
+	}
+
+	return validateRequestLength(pdu)
+}
+
+// validateRequestLength checks the length of a request against the
+// registry
+func validateRequestLength(pdu *PDU) error {
+	if pdu.FunctionCode&0x80 != 0 {
+		return ErrIllegalFunction
+	}
+	info, ok := LookupFunction(pdu.FunctionCode)
+	if !ok {
+		return ErrIllegalFunction
+	}
+	if info.RequestLength == nil {
+		return nil
+	}
+
+	request := append([]byte{pdu.FunctionCode}, pdu.Data...)
+	length, ok := info.RequestLength(request)
+	if !ok || length != len(request) {
+		return fmt.Errorf("%w: %d bytes of %s request", ErrInvalidQuantity, len(request), info.Name)
+	}
+	return nil
+}
+
+// ValidateRange checks that quantity items starting at address are
+// within the address space and at most maxQuantity
+func ValidateRange(address uint16, quantity int, maxQuantity int) error {
+	if quantity <= 0 || quantity > maxQuantity {
+		return fmt.Errorf("%w: %d, must be 1 to %d", ErrInvalidQuantity, quantity, maxQuantity)
+	}
+	if int(address)+quantity > 0x10000 {
+		return fmt.Errorf("%w: %d items at %d run past 0xFFFF", ErrInvalidAddress, quantity, address)
+	}
+	return nil
+}
+
+// validateRange checks the address and quantity starting data, which
+// must be at least minLength bytes
+func validateRange(data []byte, maxQuantity int, minLength int) error {
+	if len(data) < minLength {
+		return ErrInvalidQuantity
+	}
+	address := binary.BigEndian.Uint16(data[0:2])
+	quantity := binary.BigEndian.Uint16(data[2:4])
+	return ValidateRange(address, int(quantity), maxQuantity)
+}
+
+// validateByteCount checks the byte count at offset and that exactly that
+// many bytes follow it
+func validateByteCount(data []byte, offset int, byteCount int) error {
+	if int(data[offset]) != byteCount || len(data) != offset+1+byteCount {
+		return fmt.Errorf("%w: byte count %d for %d bytes", ErrInvalidQuantity, data[offset], len(data)-offset-1)
+	}
+	return nil
+}
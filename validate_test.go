@@ -0,0 +1,101 @@
+package modbus
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		pdu  PDU
+		want error
+	}{
+		{"read coils", PDU{FuncCodeReadCoils, []byte{0, 0, 0x07, 0xD0}}, nil},
+		{"read coils too many", PDU{FuncCodeReadCoils, []byte{0, 0, 0x07, 0xD1}}, ErrInvalidQuantity},
+		{"read holding registers", PDU{FuncCodeReadHoldingRegisters, []byte{0, 0, 0, 125}}, nil},
+		{"read holding registers zero", PDU{FuncCodeReadHoldingRegisters, []byte{0, 0, 0, 0}}, ErrInvalidQuantity},
+		{"read past 0xFFFF", PDU{FuncCodeReadHoldingRegisters, []byte{0xFF, 0xF0, 0, 100}}, ErrInvalidAddress},
+		{"read last register", PDU{FuncCodeReadInputRegisters, []byte{0xFF, 0xFF, 0, 1}}, nil},
+		{"write single coil on", PDU{FuncCodeWriteSingleCoil, []byte{0, 1, 0xFF, 0}}, nil},
+		{"write single coil bad value", PDU{FuncCodeWriteSingleCoil, []byte{0, 1, 0x12, 0}}, ErrInvalidQuantity},
+		{"write single register", PDU{FuncCodeWriteSingleRegister, []byte{0, 1, 0x12, 0x34}}, nil},
+		{"write multiple coils", PDU{FuncCodeWriteMultipleCoils, []byte{0, 0, 0, 9, 2, 0xFF, 1}}, nil},
+		{"write multiple coils byte count", PDU{FuncCodeWriteMultipleCoils, []byte{0, 0, 0, 9, 1, 0xFF}}, ErrInvalidQuantity},
+		{"write multiple registers", PDU{FuncCodeWriteMultipleRegisters, []byte{0, 0, 0, 1, 2, 0, 1}}, nil},
+		{"write multiple registers missing data", PDU{FuncCodeWriteMultipleRegisters, []byte{0, 0, 0, 2, 4, 0, 1}}, ErrInvalidQuantity},
+		{"write multiple registers past 0xFFFF", PDU{FuncCodeWriteMultipleRegisters, []byte{0xFF, 0xFF, 0, 2, 4, 0, 1, 0, 2}}, ErrInvalidAddress},
+		{"mask write register", PDU{FuncCodeMaskWriteRegister, []byte{0, 0, 0xFF, 0, 0, 1}}, nil},
+		{"read/write multiple registers", PDU{FuncCodeReadWriteMultipleRegisters, []byte{0, 0, 0, 1, 0, 8, 0, 1, 2, 0, 1}}, nil},
+		{"read/write multiple registers too many writes", PDU{FuncCodeReadWriteMultipleRegisters, []byte{0, 0, 0, 1, 0, 8, 0, 122, 244}}, ErrInvalidQuantity},
+		{"read FIFO queue", PDU{FuncCodeReadFIFOQueue, []byte{0, 4}}, nil},
+		{"unknown function", PDU{0x64, nil}, ErrIllegalFunction},
+		{"exception function code", PDU{0x83, []byte{0, 0, 0, 1}}, ErrIllegalFunction},
+		{"get comm event counter", PDU{FuncCodeGetCommEventCounter, nil}, nil},
+		{"get comm event counter with data", PDU{FuncCodeGetCommEventCounter, []byte{0}}, ErrInvalidQuantity},
+		{"diagnostics", PDU{FuncCodeDiagnostics, []byte{0}}, nil},
+		{"encapsulated interface", PDU{FuncCodeEncapsulatedInterface, nil}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRequest(&tt.pdu)
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("ValidateRequest() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Requests of every registered function code must be rejected, without
+// panicking, unless their data has the length of the function code
+func TestValidateRequestShortData(t *testing.T) {
+	t.Cleanup(func() { unregisterFunction(0x41) })
+	err := RegisterFunction(FunctionInfo{Code: 0x41, Name: "vendor", RequestLength: byteCountLength(1, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for code := 1; code < 0x80; code++ {
+		info, ok := LookupFunction(byte(code))
+		if !ok {
+			continue
+		}
+
+		full := []byte{0, 0, 0, 1, 0, 0, 0, 1, 2, 0, 0}
+		for n := 0; n < len(full); n++ {
+			pdu := &PDU{FunctionCode: byte(code), Data: full[:n]}
+			err := ValidateRequest(pdu)
+			if err == nil && info.RequestLength != nil {
+				if length, ok := info.RequestLength(append([]byte{pdu.FunctionCode}, pdu.Data...)); !ok || length != 1+n {
+					t.Errorf("%s with %d data bytes accepted", info.Name, n)
+				}
+			}
+		}
+	}
+}
+
+// unregisterFunction removes a function code registered by a test
+func unregisterFunction(code byte) {
+	functionsMu.Lock()
+	defer functionsMu.Unlock()
+	delete(functions, code)
+}
+
+func TestValidateRequestRegisteredFunction(t *testing.T) {
+	t.Cleanup(func() { unregisterFunction(0x41) })
+	err := RegisterFunction(FunctionInfo{Code: 0x41, Name: "vendor", RequestLength: byteCountLength(1, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ValidateRequest(&PDU{0x41, []byte{2, 0xAA, 0xBB}}); err != nil {
+		t.Errorf("ValidateRequest() = %v, want nil", err)
+	}
+	if err := ValidateRequest(&PDU{0x41, []byte{3, 0xAA}}); !errors.Is(err, ErrInvalidQuantity) {
+		t.Errorf("ValidateRequest() = %v, want %v", err, ErrInvalidQuantity)
+	}
+	if err := ValidateRequest(&PDU{0xC1, []byte{0}}); !errors.Is(err, ErrIllegalFunction) {
+		t.Errorf("ValidateRequest() = %v, want %v", err, ErrIllegalFunction)
+	}
+}
@@ -0,0 +1,120 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SamyFrancelet/modbus/events"
+)
+
+// WebhookNotifier posts events as JSON to an HTTP endpoint
+type WebhookNotifier struct {
+	URL string
+	// Secret, if set, signs every body with HMAC-SHA256 sent in the
+	// X-Signature-256 header as "sha256=<hex digest>"
+	Secret []byte
+	// Retries is the number of extra attempts when a post fails
+	Retries int
+	// Backoff computes the wait between attempts, 1s constant if nil
+	Backoff Backoff
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+	// OnError is called with errors of asynchronous notifications
+	OnError func(error)
+}
+
+// ConnectionEvent is the payload posted for connection state transitions
+type ConnectionEvent = events.ConnectionEvent
+
+// Notify posts payload encoded as JSON, retrying on failure
+func (w *WebhookNotifier) Notify(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	backoff := w.Backoff
+	if backoff == nil {
+		backoff = ConstantBackoff{Interval: time.Second}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, backoff.Delay(attempt)); err != nil {
+				return err
+			}
+		}
+
+		err = w.post(ctx, body)
+		if err == nil || attempt >= w.Retries {
+			return err
+		}
+	}
+}
+
+func (w *WebhookNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(w.Secret) > 0 {
+		mac := hmac.New(sha256.New, w.Secret)
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook post failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook post failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// SupervisorHandler returns a callback for Supervisor.OnEvent that posts
+// the device going online or offline. source identifies the connection in
+// the payload. Posts run in the background and report errors to OnError.
+func (w *WebhookNotifier) SupervisorHandler(source string) func(SupervisorEvent) {
+	return func(event SupervisorEvent) {
+		online := event.To == StateConnected
+		if !online && event.From != StateConnected {
+			return
+		}
+
+		payload := ConnectionEvent{
+			Source: source,
+			Online: online,
+			From:   event.From.String(),
+			To:     event.To.String(),
+			Time:   event.Time,
+		}
+		if event.Err != nil {
+			payload.Error = event.Err.Error()
+		}
+
+		go func() {
+			err := w.Notify(context.Background(), payload)
+			if err != nil && w.OnError != nil {
+				w.OnError(err)
+			}
+		}()
+	}
+}